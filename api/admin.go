@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"rocketseat/models"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type ImportItemError struct {
+	ID     uuid.UUID               `json:"id"`
+	Errors models.ValidationErrors `json:"errors"`
+}
+
+type ImportErrorResponse struct {
+	Errors []ImportItemError `json:"errors"`
+}
+
+// handleAdminExport answers the whole store, soft-deleted records included, as a JSON object
+// keyed by ID. The document is what handleAdminImport takes back.
+func (res *Resource[T]) handleAdminExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		all, err := res.list(r.Context())
+		if err != nil {
+			res.writeStoreError(w, r, err)
+			return
+		}
+
+		dump := make(map[uuid.UUID]T, len(all))
+		for _, entry := range all {
+			dump[entry.ID] = entry.Value
+		}
+
+		writeJSON(w, r, http.StatusOK, dump)
+	}
+}
+
+// handleAdminImport replaces the whole store with a document from handleAdminExport. Every
+// record is validated, and the document checked against the store's cap, before anything is
+// touched. The store has no transactions, so when a write fails anyway the previous records are
+// put back, and events for the swap are only sent once it has gone through.
+func (res *Resource[T]) handleAdminImport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		body, err := requestBody(r)
+		if err != nil {
+			writeDecodeError(w, r, err)
+			return
+		}
+
+		dump, err := decodeBody[map[uuid.UUID]T](body)
+		if err != nil {
+			writeDecodeError(w, r, err)
+			return
+		}
+
+		var itemErrors []ImportItemError
+		seenKeys := map[models.UniqueKey]uuid.UUID{}
+		for _, id := range sortedIDs(dump) {
+			value := dump[id]
+			errs := res.validate(value)
+
+			// the store is about to be emptied, so only the document itself can hold duplicates
+			if len(errs) == 0 && !isDeleted(value) {
+				for _, key := range res.uniqueKeys(value) {
+					if first, seen := seenKeys[key]; seen {
+						errs.Add(key.Field, fmt.Sprintf("duplicates %s", first))
+					} else {
+						seenKeys[key] = id
+					}
+				}
+			}
+
+			if len(errs) > 0 {
+				itemErrors = append(itemErrors, ImportItemError{ID: id, Errors: errs})
+			}
+		}
+		if len(itemErrors) > 0 {
+			writeJSON(w, r, http.StatusUnprocessableEntity, ImportErrorResponse{Errors: itemErrors})
+			return
+		}
+
+		if capper, ok := res.db.(models.Capper); ok && capper.MaxRecords() > 0 && len(dump) > capper.MaxRecords() {
+			res.writeStoreError(w, r, &models.FullError{Max: capper.MaxRecords()})
+			return
+		}
+
+		previous, err := res.removeAll(r.Context())
+		if err == nil {
+			err = res.insertAll(r.Context(), dump)
+		}
+		if err != nil {
+			res.logger.ErrorContext(r.Context(), "import failed, restoring the previous records", "error", err)
+			// the import may have failed because the request was cancelled, which mustn't stop the restore
+			restore := context.WithoutCancel(r.Context())
+			if _, removeErr := res.removeAll(restore); removeErr != nil {
+				res.logger.ErrorContext(r.Context(), "failed to remove a partial import", "error", removeErr)
+			} else if restoreErr := res.insertAll(restore, previous); restoreErr != nil {
+				res.logger.ErrorContext(r.Context(), "failed to restore records after an import", "error", restoreErr)
+			}
+			res.writeStoreError(w, r, err)
+			return
+		}
+
+		res.emitAll(r.Context(), EventDelete, previous)
+		res.emitAll(r.Context(), EventCreate, dump)
+
+		writeJSON(w, r, http.StatusOK, CountResponse{Count: len(dump)})
+	}
+}
+
+// clear removes every record and reports a delete event for each live one, including those
+// removed before a failure, so hooks like the audit log see the wipe.
+func (res *Resource[T]) clear(ctx context.Context) error {
+	removed, err := res.removeAll(ctx)
+	res.emitAll(ctx, EventDelete, removed)
+	return err
+}
+
+// removeAll removes every record, in one step when the store supports it, and returns those it
+// removed. When it fails part way they're the ones already gone.
+func (res *Resource[T]) removeAll(ctx context.Context) (map[uuid.UUID]T, error) {
+	all, err := res.db.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if clearer, ok := res.db.(models.Clearer); ok {
+		if err := clearer.Clear(ctx); err != nil {
+			return nil, err
+		}
+		return all, nil
+	}
+
+	removed := make(map[uuid.UUID]T, len(all))
+	for _, id := range sortedIDs(all) {
+		// another request may have removed it in the meantime
+		err := res.db.Delete(ctx, id)
+		if errors.Is(err, models.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return removed, err
+		}
+		removed[id] = all[id]
+	}
+
+	return removed, nil
+}
+
+// insertAll inserts every record of values in the order of sortedIDs, stopping at the first
+// that fails.
+func (res *Resource[T]) insertAll(ctx context.Context, values map[uuid.UUID]T) error {
+	for _, id := range sortedIDs(values) {
+		if err := res.db.Insert(ctx, id, values[id]); err != nil {
+			return fmt.Errorf("inserting %s: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// emitAll reports event for each live record of values, in the order of sortedIDs.
+func (res *Resource[T]) emitAll(ctx context.Context, event string, values map[uuid.UUID]T) {
+	for _, id := range sortedIDs(values) {
+		if !isDeleted(values[id]) {
+			res.emit(ctx, event, res.response(id, values[id]))
+		}
+	}
+}
+
+// sortedIDs returns the keys of m in the order list uses, so imports go through in a fixed order.
+func sortedIDs[T any](m map[uuid.UUID]T) []uuid.UUID {
+	return slices.SortedFunc(maps.Keys(m), func(a, b uuid.UUID) int {
+		return strings.Compare(a.String(), b.String())
+	})
+}
@@ -0,0 +1,137 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"rocketseat/models"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestExportClearImport(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.AllowAdmin = true
+	cfg.AllowClear = true
+	s := apitest.NewTestServerWithConfig(t, cfg)
+	insertNamed(s, "Ann", "Bob", "Cid")
+	before := s.ListUsers("")
+
+	rec := s.Do(http.MethodGet, "/admin/export", nil)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	var dump map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &dump); err != nil {
+		t.Fatal(err)
+	}
+	if len(dump) != len(before) {
+		t.Fatalf("got %d exported records, want %d", len(dump), len(before))
+	}
+
+	apitest.ExpectStatus(t, s.Do(http.MethodDelete, "/users", nil), http.StatusNoContent)
+	if users := s.ListUsers(""); len(users) != 0 {
+		t.Fatalf("got %d users after clearing, want none", len(users))
+	}
+
+	apitest.ExpectStatus(t, s.Do(http.MethodPost, "/admin/import", dump), http.StatusOK)
+
+	after := s.ListUsers("")
+	byID := func(users []apitest.User) map[string]apitest.User {
+		m := make(map[string]apitest.User, len(users))
+		for _, user := range users {
+			m[user.ID.String()] = user
+		}
+		return m
+	}
+	want, got := byID(before), byID(after)
+	if !slices.Equal(slices.Sorted(maps.Keys(got)), slices.Sorted(maps.Keys(want))) {
+		t.Fatalf("got ids %v, want %v", slices.Sorted(maps.Keys(got)), slices.Sorted(maps.Keys(want)))
+	}
+	for id, user := range want {
+		g := got[id]
+		if *g.FirstName != *user.FirstName || *g.Email != *user.Email || *g.Biography != *user.Biography {
+			t.Errorf("got %+v for %s, want %+v", g.User, id, user.User)
+		}
+	}
+}
+
+func TestAdminIsOffByDefault(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/admin/export", nil), http.StatusNotFound)
+}
+
+// failingInsertStore fails to insert the record under id, like a disk filling up part way.
+type failingInsertStore struct {
+	*models.Store[*models.User]
+	id uuid.UUID
+}
+
+func (s failingInsertStore) Insert(ctx context.Context, id uuid.UUID, user *models.User) error {
+	if id == s.id {
+		return errors.New("disk full")
+	}
+	return s.Store.Insert(ctx, id, user)
+}
+
+func TestFailedImportRestoresTheStore(t *testing.T) {
+	ctx := context.Background()
+	kept := uuid.New()
+	// the failing record sorts after the other, so the import has started writing when it fails
+	imported, failing := uuid.MustParse("00000000-0000-4000-8000-000000000001"), uuid.MustParse("00000000-0000-4000-8000-000000000002")
+	store := failingInsertStore{models.NewStore[*models.User](), failing}
+	keptUser := apitest.NewUser("Kim", "Doe", "kim@example.com")
+	if err := store.Store.Insert(ctx, kept, &keptUser); err != nil {
+		t.Fatal(err)
+	}
+
+	hook := &recordingHook{}
+	cfg := api.DefaultConfig()
+	cfg.AllowAdmin = true
+	cfg.EventHook = hook
+	cfg.Logger = discardLogger()
+	handler := api.NewHandler(store, cfg)
+
+	dump := map[uuid.UUID]models.User{
+		imported: apitest.NewUser("Ann", "Doe", "ann@example.com"),
+		failing:  apitest.NewUser("Bob", "Doe", "bob@example.com"),
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newJSONRequest(t, http.MethodPost, "/admin/import", dump))
+	apitest.ExpectStatus(t, rec, http.StatusInternalServerError)
+
+	all, err := store.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := all[kept]; len(all) != 1 || !ok {
+		t.Errorf("got %v after a failed import, want only the record from before", slices.Collect(maps.Keys(all)))
+	}
+	if len(hook.events) != 0 {
+		t.Errorf("got events %v for a failed import, want none", hook.events)
+	}
+}
+
+func TestImportOverTheCapChangesNothing(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.AllowAdmin = true
+	s := apitest.NewTestServerWithConfig(t, cfg)
+	s.Store.SetMaxRecords(2)
+	insertNamed(s, "Kim")
+
+	dump := map[uuid.UUID]models.User{}
+	for _, name := range []string{"Ann", "Bob", "Cid"} {
+		dump[uuid.New()] = apitest.NewUser(name, "Doe", strings.ToLower(name)+"@example.com")
+	}
+	apitest.DecodeError(t, s.Do(http.MethodPost, "/admin/import", dump), http.StatusInsufficientStorage)
+
+	if got := firstNames(s.ListUsers("")); !slices.Equal(got, []string{"Kim"}) {
+		t.Errorf("got %v after an import over the cap, want the store untouched", got)
+	}
+}
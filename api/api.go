@@ -1,260 +1,518 @@
 package api
 
 import (
-	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"net/http"
 	"rocketseat/models"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
 )
 
-func NewHandler(db models.DB[*models.User]) http.Handler {
-	r := chi.NewMux()
-
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.RequestID)
-	r.Use(middleware.Logger)
-
-	r.Get("/users", handleFindAll(db))
-	r.Get("/users/{id}", handleFindById(db))
-	r.Post("/users", handleInsert(db))
-	r.Put("/users/{id}", handleUpdate(db))
-	r.Delete("/users/{id}", handleDelete(db))
-
-	return r
+// Config holds the settings NewHandler applies to every route.
+type Config struct {
+	// Logger receives every log line the handler writes, with the request ID added to those about
+	// a request. Nil means slog.Default().
+	Logger *slog.Logger
+	// TrailingSlash picks how paths ending in a slash are handled: TrailingSlashStrip routes
+	// /users/ like /users, TrailingSlashRedirect answers it with a 301 to /users, and
+	// TrailingSlashStrict leaves it to the routes, where it usually doesn't match.
+	TrailingSlash string
+	// BasePath mounts the API routes under a prefix like /api/v1. Probes and metrics stay at the root.
+	BasePath string
+	CORS     CORSConfig
+	// MaxBodyBytes caps the size of request bodies; larger ones get a 413.
+	MaxBodyBytes int64
+	// StrictContentLength answers 400 to bodies longer or shorter than their Content-Length says.
+	StrictContentLength bool
+	// CompressMinBytes is the smallest response gzipped for clients that accept it. Zero disables compression.
+	CompressMinBytes int
+	// SlowRequestThreshold is how long a request may take before it's logged as a warning.
+	// Zero disables the warning.
+	SlowRequestThreshold time.Duration
+	// RequestTimeout is how long a handler gets before the client is sent a 503. Zero disables it.
+	RequestTimeout time.Duration
+	// UserAuth guards the /users routes.
+	UserAuth AuthConfig
+	// APIKey guards the /users and admin routes with a shared key, on top of UserAuth.
+	APIKey    APIKeyConfig
+	RateLimit RateLimitConfig
+	// StrictQuery makes listings answer 400 to query parameters they don't understand, so typos
+	// like ?limt=10 don't go unnoticed.
+	StrictQuery bool
+	// MaxLimit caps the limit query parameter of listings. Zero disables the cap.
+	MaxLimit int
+	// RejectOverLimit answers 400 to a limit above MaxLimit instead of clamping it to the cap.
+	RejectOverLimit bool
+	// PutCreates lets PUT /users/{id} create the user when the ID is free, answering 201.
+	PutCreates bool
+	// IdempotentDelete answers DELETE /users/{id} with 204 even when there's no such user, so
+	// retried deletes don't fail. Otherwise a missing user is a 404.
+	IdempotentDelete bool
+	// BareLists answers listings with a bare array, the total in an X-Total-Count header, for
+	// clients written before the envelope with metadata.
+	BareLists bool
+	// MaxLengths replaces the length limits of user fields, like {"biography": 2000}.
+	MaxLengths models.MaxLengths
+	// DefaultSort orders user listings that don't pass ?sort=, in the same form, like "-created_at".
+	// Empty orders them by ID.
+	DefaultSort string
+	// Relations are the objects GET /users/{id}?expand= can nest in a user, by name.
+	Relations map[string]Expander[*models.User]
+	// UniqueFields are user fields that must not repeat across live users, besides email.
+	UniqueFields []string
+	// UniqueCaseSensitive compares UniqueFields exactly. By default values differing only in
+	// case, like "Jane" and "jane", count as duplicates.
+	UniqueCaseSensitive bool
+	// AllowClear enables DELETE /users, which wipes every user. Leave it off in production.
+	AllowClear bool
+	// AllowAdmin enables GET /admin/export and POST /admin/import, which dump and replace the
+	// whole store, and /admin/maintenance, which switches Maintenance with PUT and DELETE. They
+	// take the same tokens as UserAuth, for reads too.
+	AllowAdmin bool
+	// IDGenerator picks the IDs of new users. Nil means random UUIDv4s.
+	IDGenerator IDGenerator
+	// IDFormat is how user IDs are written in responses: IDFormatCanonical, IDFormatNoHyphens or
+	// IDFormatURN. Requests are accepted in any of them. Empty means canonical.
+	IDFormat string
+	// EventHook is told about every user created, updated or deleted. Nil means none is.
+	EventHook EventHook[*models.User]
+	// Audit logs every user created, updated or deleted, with the request that did it, once
+	// Audit.Logger is set.
+	Audit AuditConfig
+	// DebugVars serves expvar's /debug/vars, with request and user counts added to Go's own
+	// variables, for environments without Prometheus.
+	DebugVars bool
+	// Pprof serves the net/http/pprof profiles under /debug/pprof/. They expose the command line
+	// and can be made to use a lot of CPU, so only turn it on where the port isn't public.
+	Pprof bool
+	// Maintenance takes every route but /healthz offline while it's on, answering 503. Nil means
+	// a switch of its own, starting off, which only the admin endpoint can flip.
+	Maintenance *Maintenance
+	// LogPanicStacks adds the stack trace to the log line written when a handler panics.
+	LogPanicStacks bool
 }
 
-type UserResponse struct {
-	ID uuid.UUID `json:"id"`
-	*models.User
-}
+const (
+	TrailingSlashStrip    = "strip"
+	TrailingSlashRedirect = "redirect"
+	TrailingSlashStrict   = "strict"
+)
 
-func validateRequestBodyFields(body io.ReadCloser, schemaObj any) (*models.User, error) {
-	// Why not build a function that gets generic schemas and checks if the request body follows them?
+func DefaultConfig() Config {
+	return Config{
+		TrailingSlash: TrailingSlashStrip,
+		DefaultSort:   "created_at",
+		CORS: CORSConfig{
+			AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "Idempotency-Key", "If-Match", "If-None-Match", "If-Unmodified-Since", requestIDHeader},
+		},
+		MaxBodyBytes:         1024 * 1024, // 1 MB
+		StrictContentLength:  true,
+		RequestTimeout:       time.Second * 5,
+		SlowRequestThreshold: time.Second,
+		CompressMinBytes:     1024,
+		MaxLimit:             defaultMaxLimit,
+		LogPanicStacks:       true,
+		RateLimit:            RateLimitConfig{Burst: 10},
+	}
+}
 
-	var user models.User
-	decoder := json.NewDecoder(body)
-	decoder.DisallowUnknownFields()
-	if err := decoder.Decode(&user); err != nil {
-		return nil, err
+func NewHandler(db models.Storage[*models.User], cfg Config) http.Handler {
+	r := chi.NewMux()
+	m := newMetrics()
+	basePath := "/" + strings.Trim(cfg.BasePath, "/")
+	if basePath == "/" {
+		basePath = ""
 	}
 
-	schemaObjJson, err := json.Marshal(schemaObj)
-	if err != nil {
-		return nil, err
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
 	}
-	var schemaObjMap map[string]interface{}
-	if err := json.Unmarshal(schemaObjJson, &schemaObjMap); err != nil {
-		return nil, err
+	// request lines and handler errors need the request ID whatever logger the caller passed
+	if _, ok := logger.Handler().(ContextHandler); !ok {
+		logger = slog.New(ContextHandler{Handler: logger.Handler()})
 	}
 
-	userJson, err := json.Marshal(user)
-	if err != nil {
-		return nil, err
-	}
-	var userMap map[string]interface{}
-	if err := json.Unmarshal(userJson, &userMap); err != nil {
-		return nil, err
+	r.Use(withLogger(logger))
+	r.Use(requestID)
+	switch cfg.TrailingSlash {
+	case TrailingSlashStrip:
+		r.Use(middleware.StripSlashes)
+	case TrailingSlashRedirect:
+		r.Use(middleware.RedirectSlashes)
 	}
 
-	for key := range schemaObjMap {
-		if userMap[key] == nil {
-			return nil, errors.New("please provide FirstName LastName and bio for the user")
-		}
+	// browsers have to be allowed to send the key on cross-origin requests
+	if len(cfg.APIKey.Keys) > 0 {
+		cfg.CORS.AllowedHeaders = append(slices.Clone(cfg.CORS.AllowedHeaders), cfg.APIKey.header())
 	}
 
-	return &user, nil
-}
+	r.Use(recoverer(logger, cfg.LogPanicStacks))
+	r.Use(m.middleware)
+	// preflights come before routing, since routes registered for one method would answer them 405
+	r.Use(corsMiddleware(cfg.CORS))
 
-func handleFindAll(db models.DB[*models.User]) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var result []UserResponse
+	maintenance := cfg.Maintenance
+	if maintenance == nil {
+		maintenance = NewMaintenance(0)
+	}
+	r.Use(maintenance.middleware("/healthz", basePath+"/admin/maintenance"))
+	r.MethodNotAllowed(handleMethodNotAllowed(r))
+
+	users := NewResource[*models.User](db).WithLogger(logger)
+	users.allowClear = cfg.AllowClear
+	users.strictQuery = cfg.StrictQuery
+	users.maxLimit = cfg.MaxLimit
+	users.rejectOverLimit = cfg.RejectOverLimit
+	users.bareLists = cfg.BareLists
+	users.putCreates = cfg.PutCreates
+	users.idempotentDelete = cfg.IdempotentDelete
+	users.maxLengths = cfg.MaxLengths
+	users.WithUnique(cfg.UniqueFields...)
+	users.uniqueCaseSensitive = cfg.UniqueCaseSensitive
+	users.WithDefaultSort(cfg.DefaultSort)
+	for name, expand := range cfg.Relations {
+		users.WithRelation(name, expand)
+	}
+	if cfg.IDGenerator != nil {
+		users.ids = cfg.IDGenerator
+	}
+	users.idFormat = cfg.IDFormat
+	if cfg.EventHook != nil {
+		users.WithHook(cfg.EventHook)
+	}
+	if cfg.Audit.Logger != nil {
+		users.WithHook(NewAuditLog[*models.User](cfg.Audit))
+	}
 
-		for key, value := range db {
-			result = append(result, UserResponse{ID: key, User: value})
-		}
+	// probes and scrapers hit these constantly, so they sit outside the request logger
+	r.Get("/healthz", handleHealth())
+	r.Get("/readyz", handleReady(db, logger))
+	r.Method(http.MethodGet, "/metrics", m.handler())
+	if cfg.DebugVars {
+		r.Get("/debug/vars", handleDebugVars(m, users))
+	}
+	if cfg.Pprof {
+		mountPprof(r)
+	}
 
-		jsonResult, err := json.Marshal(result)
-		if err != nil {
-			http.Error(w, "Error parsing response", http.StatusInternalServerError)
+	r.Group(func(r chi.Router) {
+		r.Use(requestLogger(logger, cfg.SlowRequestThreshold))
+		r.Use(rateLimit(cfg.RateLimit))
+		// the length check sits under MaxBytesReader, so oversized bodies still get their 413
+		r.Use(checkContentLength(cfg.StrictContentLength))
+		r.Use(limitBody(cfg.MaxBodyBytes))
+		r.Use(compress(cfg.CompressMinBytes))
+
+		// the users routes are mounted, so they take the timeout once matched instead
+		r.Group(func(r chi.Router) {
+			r.Use(requireAPIKey(cfg.APIKey))
+			r.Use(requireAuth(cfg.UserAuth))
+			users.WithMiddleware(timeout(cfg.RequestTimeout)).RegisterRoutes(r, basePath+"/users")
+		})
+
+		r = r.With(timeout(cfg.RequestTimeout))
+		if cfg.AllowAdmin {
+			r.Group(func(r chi.Router) {
+				r.Use(requireAPIKey(cfg.APIKey))
+				r.Use(requireAuth(AuthConfig{Secret: cfg.UserAuth.Secret, ProtectReads: true}))
+				r.Get(basePath+"/admin/export", users.handleAdminExport())
+				r.Post(basePath+"/admin/import", users.handleAdminImport())
+				r.Get(basePath+"/admin/maintenance", maintenance.handleMaintenance())
+				r.Put(basePath+"/admin/maintenance", maintenance.handleMaintenance())
+				r.Delete(basePath+"/admin/maintenance", maintenance.handleMaintenance())
+			})
 		}
-		w.WriteHeader(http.StatusOK)
-		w.Write(jsonResult)
-	}
+
+		r.Get(basePath+"/openapi.json", handleOpenAPI(users))
+	})
+
+	return r
 }
 
-func handleFindById(db models.DB[*models.User]) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		id := chi.URLParam(r, "id")
+var routeMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
 
-		parsedID, err := uuid.Parse(id)
-		if err != nil {
-			http.Error(w, "Invalid ID", http.StatusBadRequest)
-		}
+// handleMethodNotAllowed replaces chi's empty 405 with a JSON error. chi doesn't pass the
+// allowed methods on to custom handlers, and matching against routes directly is fooled by
+// mounted subrouters, which accept every method at their root. So on first use every route is
+// walked into a flat probe router that can be asked which methods a path supports.
+func handleMethodNotAllowed(routes chi.Routes) http.HandlerFunc {
+	var once sync.Once
+	probe := chi.NewMux()
+	noop := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
 
-		user, ok := db[parsedID]
-		if !ok {
-			http.Error(w, "User not found", http.StatusNotFound)
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+				if len(route) > 1 {
+					route = strings.TrimSuffix(route, "/")
+				}
+				probe.Method(method, route, noop)
+				return nil
+			})
+		})
+
+		var allowed []string
+		for _, method := range routeMethods {
+			if probe.Match(chi.NewRouteContext(), method, r.URL.Path) {
+				allowed = append(allowed, method)
+			}
 		}
 
-		userResponse := UserResponse{ID: parsedID, User: user}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, fmt.Sprintf("Method %s is not allowed on %s", r.Method, r.URL.Path))
+	}
+}
 
-		jsonUser, err := json.Marshal(userResponse)
-		if err != nil {
-			http.Error(w, "Error parsing response", http.StatusInternalServerError)
-		}
+type StatusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
 
-		w.WriteHeader(http.StatusOK)
-		w.Write(jsonUser)
+// handleHealth reports the process is up and serving, without checking any dependency.
+func handleHealth() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, r, http.StatusOK, StatusResponse{Status: "ok"})
 	}
 }
 
-func handleInsert(db models.DB[*models.User]) http.HandlerFunc {
+// handleReady reports whether the storage backend can serve requests, for backends that can tell.
+func handleReady(db any, logger *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		defer r.Body.Close()
-		var userModel models.User
-		user, err := validateRequestBodyFields(r.Body, userModel)
-		if err != nil {
-			slog.Error("Request body validation error", "error", err)
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+		if pinger, ok := db.(models.Pinger); ok {
+			if err := pinger.Ping(r.Context()); err != nil {
+				logger.WarnContext(r.Context(), "storage not ready", "error", err)
+				writeJSON(w, r, http.StatusServiceUnavailable, StatusResponse{Status: "unavailable", Error: err.Error()})
+				return
+			}
 		}
 
-		userId := uuid.New()
-
-		db[userId] = user
-
-		userResponse := UserResponse{ID: userId, User: user}
-
-		jsonUser, err := json.Marshal(userResponse)
-		if err != nil {
-			http.Error(w, "Error while parsing the response", http.StatusInternalServerError)
-		}
-		w.WriteHeader(http.StatusCreated)
-		w.Write(jsonUser)
+		writeJSON(w, r, http.StatusOK, StatusResponse{Status: "ok"})
 	}
 }
 
-func handleUpdate(db models.DB[*models.User]) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		defer r.Body.Close()
+const (
+	ErrCodeInvalidID            = "invalid_id"
+	ErrCodeInvalidQuery         = "invalid_query"
+	ErrCodeInvalidBody          = "invalid_body"
+	ErrCodeBodyTooLarge         = "body_too_large"
+	ErrCodeUnsupportedMediaType = "unsupported_media_type"
+	ErrCodeValidationFailed     = "validation_failed"
+	ErrCodeNotFound             = "not_found"
+	ErrCodeConflict             = "conflict"
+	ErrCodeMethodNotAllowed     = "method_not_allowed"
+	ErrCodeUnauthorized         = "unauthorized"
+	ErrCodeRateLimited          = "rate_limited"
+	ErrCodeTimeout              = "timeout"
+	ErrCodeStorageFull          = "storage_full"
+	ErrCodeMaintenance          = "maintenance"
+	ErrCodeInvalidVersion       = "invalid_version"
+	ErrCodeVersionMismatch      = "version_mismatch"
+	ErrCodePreconditionRequired = "precondition_required"
+	ErrCodePreconditionFailed   = "precondition_failed"
+	ErrCodeInternal             = "internal_error"
+)
 
-		id := chi.URLParam(r, "id")
-		parsedID, err := uuid.Parse(id)
-		if err != nil {
-			http.Error(w, "Invalid ID", http.StatusBadRequest)
-		}
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Resource and ID name the record a not_found error is about.
+	Resource string `json:"resource,omitempty"`
+	ID       string `json:"id,omitempty"`
+}
 
-		var userModel models.User
-		user, err := validateRequestBodyFields(r.Body, userModel)
-		if err != nil {
-			slog.Error("Request body validation error", "error", err)
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
-		}
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
 
-		_, ok := db[parsedID]
-		if !ok {
-			http.Error(w, "User not found", http.StatusNotFound)
-		}
+// writeError replaces http.Error so clients always get a JSON body they can parse.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeErrorDetail(w, status, ErrorDetail{Code: code, Message: message})
+}
 
-		db[parsedID] = user
+func writeErrorDetail(w http.ResponseWriter, status int, detail ErrorDetail) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
 
-		userResponse := UserResponse{ID: parsedID, User: user}
+	// the body is a small fixed struct, so this only fails once the client has gone away
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Error: detail})
+}
 
-		jsonUser, err := json.Marshal(userResponse)
-		if err != nil {
-			http.Error(w, "Error while parsing the response", http.StatusInternalServerError)
-		}
-		w.WriteHeader(http.StatusOK)
-		w.Write(jsonUser)
+// limitBody wraps every request body in http.MaxBytesReader so oversized payloads fail while
+// being decoded instead of being read into memory.
+func limitBody(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if maxBytes > 0 && r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
 	}
 }
-func handleDelete(db models.DB[*models.User]) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		defer r.Body.Close()
-
-		id := chi.URLParam(r, "id")
-
-		parsedID, err := uuid.Parse(id)
-		if err != nil {
-			http.Error(w, "Invalid ID", http.StatusBadRequest)
-		}
 
-		_, ok := db[parsedID]
+// writeDecodeError reports a request body that couldn't be decoded, telling a body over the
+// size limit or in the wrong format apart from a missing, malformed or mismatched one.
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, ErrCodeBodyTooLarge, fmt.Sprintf("Request body must not exceed %d bytes", tooLarge.Limit))
+		return
+	}
+	if errors.Is(err, errContentLengthMismatch) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidBody, "Request body length doesn't match its Content-Length")
+		return
+	}
+	if errors.Is(err, errUnsupportedMediaType) {
+		writeError(w, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType, "Content-Type must be application/json or application/x-msgpack")
+		return
+	}
 
-		if !ok {
-			http.Error(w, "User not found", http.StatusNotFound)
-		}
+	loggerFrom(r.Context()).ErrorContext(r.Context(), "Request body decoding error", "error", err)
+
+	var syntaxErr *json.SyntaxError
+	var duplicate *duplicateKeyError
+	switch {
+	case errors.As(err, &duplicate):
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidBody, duplicate.Error())
+	case errors.Is(err, io.EOF):
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidBody, "Request body is required")
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.As(err, &syntaxErr):
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidBody, "Malformed JSON")
+	default:
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidBody, "Invalid request body")
+	}
+}
 
-		delete(db, parsedID)
+type ValidationErrorResponse struct {
+	Errors models.ValidationErrors `json:"errors"`
+}
 
-		w.WriteHeader(http.StatusNoContent)
-	}
+// writeValidationErrors reports every invalid field at once with a 422.
+func writeValidationErrors(w http.ResponseWriter, r *http.Request, errs models.ValidationErrors) {
+	writeJSON(w, r, http.StatusUnprocessableEntity, ValidationErrorResponse{Errors: errs})
 }
 
-func handleInsert_EXPERIMENTAL(db models.DB[*models.User]) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// there are two common ways to read a request.
+// UserResponse is kept for callers that still refer to the user-specific response type.
+type UserResponse = Response[*models.User]
 
-		// =======================================================
+// NewUserResponse builds a UserResponse, returning an error when user is nil.
+func NewUserResponse(id uuid.UUID, user *models.User) (UserResponse, error) {
+	return NewResponse(id, user)
+}
 
-		// first is using the io.ReadAll, which reads the http request stream entirely
-		// however, there is a catch - if the user sends a body too large, it could overload system memory, meaning its prone to attacks
-		// but there are safe ways to do so
+// writeJSON marshals v and writes it with the given status, falling back to a JSON 500 if that fails.
+// Clients that prefer MessagePack in their Accept header get it instead of JSON.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	data, contentType, err := encodeResponse(r, v)
+	if err != nil {
+		loggerFrom(r.Context()).ErrorContext(r.Context(), "failed to marshal response", "error", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "Error while parsing the response")
+		return
+	}
 
-		// this way, we limit reading on the body by a determined ammount of bytes
-		maxBytes := int64(1024 * 1024) // 1 MB limit
-		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
-		// IMPORTANT: Once the body stream is read, it's consumed and cannot be read again.
-		bodyBytes, err := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	w.Write(data)
+}
 
-		if err != nil {
-			slog.Error("error reading request body", "error", err)
-			http.Error(w, "Error reading request body", http.StatusInternalServerError)
-			return
-		}
+// writeEntity writes a single record like writeJSON, adding a strong ETag derived from the
+// body. A GET whose If-None-Match already holds that ETag gets a bodiless 304 instead.
+func writeEntity(w http.ResponseWriter, r *http.Request, status int, v any) {
+	data, contentType, err := encodeResponse(r, v)
+	if err != nil {
+		loggerFrom(r.Context()).ErrorContext(r.Context(), "failed to marshal response", "error", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "Error while parsing the response")
+		return
+	}
 
-		var payload models.User
-		if err := json.Unmarshal(bodyBytes, &payload); err != nil {
-			slog.Error("error unmarshaling request body to payload", "error", err)
-			http.Error(w, "Error unmarshaling request body to payload", http.StatusBadRequest)
-			return
-		}
+	etag := entityTag(data)
+	w.Header().Set("ETag", etag)
+	w.Header().Add("Vary", "Accept")
 
-		defer r.Body.Close()
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 
-		// reseting the body is necessary to read it again
-		r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	w.Write(data)
+}
 
-		// =======================================================
+// entityTag is the strong ETag of a response body.
+func entityTag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
 
-		// second is using json.NewDecoder, which simply decodes the body stream into the struct.
-		// this way is more straightforward and safe
+// etagMatches reports whether an If-None-Match style header lists etag. As RFC 9110 asks for
+// If-None-Match, weak validators compare equal to their strong counterpart.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
 
-		var user models.User
-		decoder := json.NewDecoder(r.Body)
-		decoder.DisallowUnknownFields()
-		if err := decoder.Decode(&user); err != nil {
-			slog.Error("error decoding request body to user", "error", err)
-			http.Error(w, "Error unmarshaling request body to payload", http.StatusBadRequest)
-			return
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
 		}
+	}
 
-		// =======================================================
+	return false
+}
 
-		// from now on, things are handled equally for both methods
+const (
+	defaultLimit    = 20
+	defaultMaxLimit = 100
+	defaultOffset   = 0
+)
 
-		w.Header().Set("Content-Type", "application/json")
-		data, err := json.Marshal(user)
-		if err != nil {
-			slog.Error("error marshaling request body to payload", "error", err)
-			return
-		}
-		w.Write(data)
+// parseNonNegativeQuery reads an integer query parameter, falling back to def when it's absent.
+func parseNonNegativeQuery(r *http.Request, key string, def int) (int, error) {
+	raw := r.URL.Query().Get(key)
+	if raw == "" {
+		return def, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, fmt.Errorf("%s is out of range, it must be at most %d", key, math.MaxInt)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number", key)
 	}
+	if value < 0 {
+		return 0, fmt.Errorf("%s must not be negative", key)
+	}
+
+	return value, nil
 }
@@ -0,0 +1,55 @@
+package api_test
+
+import (
+	"net/http"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"testing"
+)
+
+func TestAPIKey(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.APIKey = api.APIKeyConfig{Keys: []string{"first-key", "second-key"}}
+	s := apitest.NewTestServerWithConfig(t, cfg)
+
+	tests := []struct {
+		name, key string
+		want      int
+	}{
+		{"valid", "second-key", http.StatusOK},
+		{"invalid", "wrong-key", http.StatusUnauthorized},
+		{"missing", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := s.NewRequest(http.MethodGet, "/users", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			rec := s.Serve(req)
+			if tt.want == http.StatusOK {
+				apitest.ExpectStatus(t, rec, tt.want)
+				return
+			}
+			if got := apitest.DecodeError(t, rec, tt.want); got.Code != api.ErrCodeUnauthorized {
+				t.Errorf("got code %q, want %q", got.Code, api.ErrCodeUnauthorized)
+			}
+		})
+	}
+
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/healthz", nil), http.StatusOK)
+}
+
+func TestAPIKeyCustomHeader(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.APIKey = api.APIKeyConfig{Header: "Authorization-Key", Keys: []string{"secret"}}
+	s := apitest.NewTestServerWithConfig(t, cfg)
+
+	req := s.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("X-API-Key", "secret")
+	apitest.ExpectStatus(t, s.Serve(req), http.StatusUnauthorized)
+
+	req = s.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Authorization-Key", "secret")
+	apitest.ExpectStatus(t, s.Serve(req), http.StatusOK)
+}
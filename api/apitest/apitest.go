@@ -0,0 +1,156 @@
+// Package apitest wires the API handler to a fresh in-memory store for tests, with helpers that
+// send requests through httptest and decode the responses into typed values.
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"rocketseat/api"
+	"rocketseat/models"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// User is a user as the API returns it, with its ID alongside the model's fields.
+type User struct {
+	ID uuid.UUID `json:"id"`
+	models.User
+}
+
+// Server is an API handler backed by its own in-memory store. Helpers fail the test on any
+// response they didn't expect, so tests only have to check what they're about.
+type Server struct {
+	Handler http.Handler
+	Store   *models.Store[*models.User]
+	t       testing.TB
+}
+
+// NewTestServer returns a Server using api.DefaultConfig, logging to the test log.
+func NewTestServer(t testing.TB) *Server {
+	return NewTestServerWithConfig(t, api.DefaultConfig())
+}
+
+// NewTestServerWithConfig returns a Server using cfg. A nil cfg.Logger logs to the test log,
+// so handler logs only show up for failing tests.
+func NewTestServerWithConfig(t testing.TB, cfg api.Config) *Server {
+	t.Helper()
+
+	if cfg.Logger == nil {
+		cfg.Logger = slogToTest(t)
+	}
+
+	store := models.NewStore[*models.User]()
+	return &Server{Handler: api.NewHandler(store, cfg), Store: store, t: t}
+}
+
+// NewUser returns a user with every required field set.
+func NewUser(firstName, lastName, email string) models.User {
+	biography := "Written by a test"
+	return models.User{FirstName: &firstName, LastName: &lastName, Biography: &biography, Email: &email}
+}
+
+// Do sends a request with body encoded as JSON, or no body when it's nil.
+func (s *Server) Do(method, path string, body any) *httptest.ResponseRecorder {
+	s.t.Helper()
+	return s.Serve(s.NewRequest(method, path, body))
+}
+
+// Serve sends req, which tests build with NewRequest when they need to set headers.
+func (s *Server) Serve(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.Handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// NewRequest builds the request Do would send, for Serve.
+func (s *Server) NewRequest(method, path string, body any) *http.Request {
+	s.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("encoding %s %s body: %v", method, path, err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return req
+}
+
+// InsertUser creates user through POST /users and returns it as stored.
+func (s *Server) InsertUser(user models.User) User {
+	s.t.Helper()
+	return decode[User](s.t, s.Do(http.MethodPost, "/users", user), http.StatusCreated)
+}
+
+// GetUser fetches the user with id through GET /users/{id}.
+func (s *Server) GetUser(id uuid.UUID) User {
+	s.t.Helper()
+	return decode[User](s.t, s.Do(http.MethodGet, "/users/"+id.String(), nil), http.StatusOK)
+}
+
+// UpdateUser replaces the user with id through PUT /users/{id}. PUT needs the version being
+// replaced, so set user.Version to the one last read.
+func (s *Server) UpdateUser(id uuid.UUID, user models.User) User {
+	s.t.Helper()
+	return decode[User](s.t, s.Do(http.MethodPut, "/users/"+id.String(), user), http.StatusOK)
+}
+
+// DeleteUser deletes the user with id through DELETE /users/{id}.
+func (s *Server) DeleteUser(id uuid.UUID) {
+	s.t.Helper()
+	ExpectStatus(s.t, s.Do(http.MethodDelete, "/users/"+id.String(), nil), http.StatusNoContent)
+}
+
+// ListUsers fetches the first page of GET /users with the given query, like "limit=5".
+func (s *Server) ListUsers(query string) []User {
+	s.t.Helper()
+
+	page := decode[struct {
+		Data []User `json:"data"`
+	}](s.t, s.Do(http.MethodGet, "/users?"+query, nil), http.StatusOK)
+	return page.Data
+}
+
+// ExpectStatus fails the test unless rec answered with status.
+func ExpectStatus(t testing.TB, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+
+	if rec.Code != status {
+		t.Fatalf("got status %d, want %d; body: %s", rec.Code, status, rec.Body.String())
+	}
+}
+
+// DecodeError reads the JSON error body of a failed request.
+func DecodeError(t testing.TB, rec *httptest.ResponseRecorder, status int) api.ErrorDetail {
+	t.Helper()
+	return decode[api.ErrorResponse](t, rec, status).Error
+}
+
+// DecodeValidationErrors reads the field errors of a 422.
+func DecodeValidationErrors(t testing.TB, rec *httptest.ResponseRecorder) models.ValidationErrors {
+	t.Helper()
+	return decode[api.ValidationErrorResponse](t, rec, http.StatusUnprocessableEntity).Errors
+}
+
+func decode[T any](t testing.TB, rec *httptest.ResponseRecorder, status int) T {
+	t.Helper()
+	ExpectStatus(t, rec, status)
+
+	var value T
+	if err := json.Unmarshal(rec.Body.Bytes(), &value); err != nil {
+		t.Fatalf("decoding response %s: %v", rec.Body.String(), err)
+	}
+
+	return value
+}
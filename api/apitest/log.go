@@ -0,0 +1,39 @@
+package apitest
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testWriter sends each log line to the test log, where it's only shown for failing tests.
+// Lines written once the test is over are dropped: goroutines outliving it, like webhook
+// retries, may still log, and t.Log panics after a test has completed.
+type testWriter struct {
+	t    testing.TB
+	mu   sync.Mutex
+	done bool
+}
+
+func (w *testWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.done {
+		w.t.Helper()
+		w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	}
+	return len(p), nil
+}
+
+func slogToTest(t testing.TB) *slog.Logger {
+	w := &testWriter{t: t}
+	t.Cleanup(func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.done = true
+	})
+
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
@@ -0,0 +1,37 @@
+package apitest
+
+import "testing"
+
+// finishingTB counts the lines logged to it and runs its cleanups when finish is called, like
+// a test completing.
+type finishingTB struct {
+	testing.TB
+	lines    int
+	cleanups []func()
+}
+
+func (tb *finishingTB) Helper() {}
+
+func (tb *finishingTB) Log(...any) { tb.lines++ }
+
+func (tb *finishingTB) Cleanup(f func()) { tb.cleanups = append(tb.cleanups, f) }
+
+func (tb *finishingTB) finish() {
+	for _, f := range tb.cleanups {
+		f()
+	}
+}
+
+func TestLogAfterTestIsDropped(t *testing.T) {
+	tb := &finishingTB{TB: t}
+	logger := slogToTest(tb)
+
+	logger.Info("while running")
+	tb.finish()
+	// t.Log would panic here on a real test
+	logger.Info("after the test")
+
+	if tb.lines != 1 {
+		t.Errorf("got %d lines logged, want only the one from while the test ran", tb.lines)
+	}
+}
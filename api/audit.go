@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"slices"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const redacted = "[redacted]"
+
+type AuditConfig struct {
+	// Logger receives one entry per change. Nil disables the audit log.
+	Logger *slog.Logger
+	// Diff adds the fields an update changed to its entry, with their old and new values.
+	Diff bool
+	// Redact lists fields whose values never reach a diff, like "email". Changes to them are
+	// still recorded, as "[redacted]".
+	Redact []string
+}
+
+// AuditLog is an EventHook writing an entry for every change, with the request that made it.
+type AuditLog[T any] struct {
+	cfg AuditConfig
+}
+
+func NewAuditLog[T any](cfg AuditConfig) *AuditLog[T] {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	return &AuditLog[T]{cfg: cfg}
+}
+
+type auditChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+func (a *AuditLog[T]) OnCreate(ctx context.Context, record Response[T]) error {
+	a.write(ctx, EventCreate, record, nil)
+	return nil
+}
+
+func (a *AuditLog[T]) OnUpdate(ctx context.Context, record Response[T]) error {
+	a.write(ctx, EventUpdate, record, nil)
+	return nil
+}
+
+// OnUpdateFrom makes AuditLog an UpdateDiffHook, so it can tell what an update changed.
+func (a *AuditLog[T]) OnUpdateFrom(ctx context.Context, before, after Response[T]) error {
+	if !a.cfg.Diff {
+		return a.OnUpdate(ctx, after)
+	}
+
+	diff, err := a.diff(before.Model, after.Model)
+	if err != nil {
+		return err
+	}
+
+	a.write(ctx, EventUpdate, after, diff)
+	return nil
+}
+
+func (a *AuditLog[T]) OnDelete(ctx context.Context, record Response[T]) error {
+	a.write(ctx, EventDelete, record, nil)
+	return nil
+}
+
+// write logs the entry with the request ID as an attribute of its own rather than through
+// ContextHandler, so it's there whatever handler the audit logger was built with.
+func (a *AuditLog[T]) write(ctx context.Context, event string, record Response[T], diff map[string]auditChange) {
+	attrs := []slog.Attr{
+		slog.String("event", event),
+		slog.String("id", record.ID.String()),
+		slog.String("request_id", middleware.GetReqID(ctx)),
+	}
+	if diff != nil {
+		attrs = append(attrs, slog.Any("diff", diff))
+	}
+
+	a.cfg.Logger.LogAttrs(context.Background(), slog.LevelInfo, "audit", attrs...)
+}
+
+// diff compares the JSON fields of before and after, masking the values of redacted ones.
+func (a *AuditLog[T]) diff(before, after T) (map[string]auditChange, error) {
+	from, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := map[string]auditChange{}
+	for field, value := range to {
+		if old, ok := from[field]; ok && reflect.DeepEqual(old, value) {
+			continue
+		}
+		diff[field] = a.change(field, from[field], value)
+	}
+	for field, value := range from {
+		if _, ok := to[field]; !ok {
+			diff[field] = a.change(field, value, nil)
+		}
+	}
+
+	return diff, nil
+}
+
+func (a *AuditLog[T]) change(field string, from, to any) auditChange {
+	if slices.Contains(a.cfg.Redact, field) {
+		return auditChange{From: redacted, To: redacted}
+	}
+
+	return auditChange{From: from, To: to}
+}
+
+// jsonFields decodes the JSON form of value into its fields, so models compare the way they're
+// written out.
+func jsonFields(value any) (map[string]any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,116 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// auditEntries decodes the JSON lines logged to buf into event and id pairs.
+func auditEntries(t *testing.T, buf *bytes.Buffer) [][2]string {
+	t.Helper()
+
+	var entries [][2]string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry struct {
+			Event string `json:"event"`
+			ID    string `json:"id"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, [2]string{entry.Event, entry.ID})
+	}
+	buf.Reset()
+	return entries
+}
+
+func newAuditedServer(t *testing.T) (*apitest.Server, *bytes.Buffer) {
+	var audit bytes.Buffer
+	cfg := api.DefaultConfig()
+	cfg.AllowClear = true
+	cfg.AllowAdmin = true
+	cfg.Audit = api.AuditConfig{Logger: slog.New(slog.NewJSONHandler(&audit, nil))}
+	return apitest.NewTestServerWithConfig(t, cfg), &audit
+}
+
+func TestAuditLogRecordsEveryChange(t *testing.T) {
+	s, audit := newAuditedServer(t)
+
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	user.FirstName = ptr("Janet")
+	s.UpdateUser(user.ID, user.User)
+	s.DeleteUser(user.ID)
+
+	id := user.ID.String()
+	want := [][2]string{{"create", id}, {"update", id}, {"delete", id}}
+	if got := auditEntries(t, audit); !slices.Equal(got, want) {
+		t.Errorf("got audit entries %v, want %v", got, want)
+	}
+}
+
+func TestAuditLogRecordsClear(t *testing.T) {
+	s, audit := newAuditedServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	auditEntries(t, audit)
+
+	apitest.ExpectStatus(t, s.Do(http.MethodDelete, "/users", nil), http.StatusNoContent)
+
+	want := [][2]string{{"delete", user.ID.String()}}
+	if got := auditEntries(t, audit); !slices.Equal(got, want) {
+		t.Errorf("got audit entries %v, want %v", got, want)
+	}
+}
+
+func TestAuditLogRecordsImport(t *testing.T) {
+	s, audit := newAuditedServer(t)
+	old := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	auditEntries(t, audit)
+
+	imported := apitest.NewUser("John", "Roe", "john@example.com")
+	rec := s.Do(http.MethodPost, "/admin/import", map[string]any{"6ba7b810-9dad-11d1-80b4-00c04fd430c8": imported})
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+
+	want := [][2]string{{"delete", old.ID.String()}, {"create", "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}}
+	if got := auditEntries(t, audit); !slices.Equal(got, want) {
+		t.Errorf("got audit entries %v, want %v", got, want)
+	}
+}
+
+func TestAuditEntryForDelete(t *testing.T) {
+	s, audit := newAuditedServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	audit.Reset()
+
+	before := time.Now()
+	req := s.NewRequest(http.MethodDelete, "/users/"+user.ID.String(), nil)
+	req.Header.Set("X-Request-ID", "delete-jane")
+	apitest.ExpectStatus(t, s.Serve(req), http.StatusNoContent)
+
+	var entry struct {
+		Time      time.Time `json:"time"`
+		Msg       string    `json:"msg"`
+		Event     string    `json:"event"`
+		ID        string    `json:"id"`
+		RequestID string    `json:"request_id"`
+	}
+	if err := json.Unmarshal(audit.Bytes(), &entry); err != nil {
+		t.Fatalf("got %s, want one JSON entry: %v", audit, err)
+	}
+	if entry.Msg != "audit" || entry.Event != api.EventDelete || entry.ID != user.ID.String() || entry.RequestID != "delete-jane" {
+		t.Errorf("got %+v, want the delete of %s by delete-jane", entry, user.ID)
+	}
+	if entry.Time.Before(before.Truncate(time.Millisecond)) {
+		t.Errorf("got time %v, want it at or after %v", entry.Time, before)
+	}
+}
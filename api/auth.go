@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type AuthConfig struct {
+	// Secret is the HMAC key bearer tokens must be signed with. Empty disables authentication.
+	Secret []byte
+	// ProtectReads also requires a token for GET, HEAD and OPTIONS requests.
+	ProtectReads bool
+}
+
+type subjectKey struct{}
+
+// SubjectFromContext returns the sub claim of the token that authenticated the request, if any.
+func SubjectFromContext(ctx context.Context) (string, bool) {
+	subject, ok := ctx.Value(subjectKey{}).(string)
+	return subject, ok
+}
+
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// requireAuth rejects requests without a valid HMAC-signed JWT in the Authorization header.
+// Reads stay public unless cfg.ProtectReads is set.
+func requireAuth(cfg AuthConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(cfg.Secret) == 0 || (!cfg.ProtectReads && isReadMethod(r.Method)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, err := parseBearerToken(r.Header.Get("Authorization"), cfg.Secret)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				writeError(w, http.StatusUnauthorized, ErrCodeUnauthorized, err.Error())
+				return
+			}
+
+			ctx := r.Context()
+			if subject, err := claims.GetSubject(); err == nil && subject != "" {
+				ctx = context.WithValue(ctx, subjectKey{}, subject)
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func parseBearerToken(header string, secret []byte) (jwt.MapClaims, error) {
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, errors.New("Missing bearer token")
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}))
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return nil, errors.New("Token has expired")
+	case err != nil:
+		return nil, errors.New("Invalid bearer token")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,58 @@
+package api_test
+
+import (
+	"fmt"
+	"net/http"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testSecret = []byte("test-secret")
+
+func signToken(t *testing.T, expiresAt time.Time) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "jane", "exp": expiresAt.Unix()})
+	signed, err := token.SignedString(testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestWritesNeedAValidToken(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.UserAuth = api.AuthConfig{Secret: testSecret}
+	s := apitest.NewTestServerWithConfig(t, cfg)
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"valid token", "Bearer " + signToken(t, time.Now().Add(time.Hour)), http.StatusCreated},
+		{"expired token", "Bearer " + signToken(t, time.Now().Add(-time.Hour)), http.StatusUnauthorized},
+		{"missing token", "", http.StatusUnauthorized},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := s.NewRequest(http.MethodPost, "/users", apitest.NewUser("Jane", "Doe", fmt.Sprintf("jane%d@example.com", i)))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := s.Serve(req)
+
+			apitest.ExpectStatus(t, rec, tt.want)
+			if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("got a 401 without WWW-Authenticate")
+			}
+		})
+	}
+
+	// reads stay public unless ProtectReads is set
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/users", nil), http.StatusOK)
+}
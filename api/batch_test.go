@@ -0,0 +1,84 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"rocketseat/models"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestBatchInsertCreatesEveryItem(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	rec := s.Do(http.MethodPost, "/users/batch", []models.User{
+		apitest.NewUser("Jane", "Doe", "jane@example.com"),
+		apitest.NewUser("John", "Roe", "john@example.com"),
+		apitest.NewUser("Jim", "Poe", "jim@example.com"),
+	})
+	apitest.ExpectStatus(t, rec, http.StatusCreated)
+
+	var created []apitest.User
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 3 {
+		t.Fatalf("got %d users back, want 3", len(created))
+	}
+	for _, user := range created {
+		s.GetUser(user.ID)
+	}
+}
+
+func TestBatchInsertWithInvalidItemCreatesNothing(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	invalid := apitest.NewUser("Jim", "Poe", "not-an-email")
+	rec := s.Do(http.MethodPost, "/users/batch", []models.User{
+		apitest.NewUser("Jane", "Doe", "jane@example.com"),
+		apitest.NewUser("John", "Roe", "john@example.com"),
+		invalid,
+	})
+	apitest.ExpectStatus(t, rec, http.StatusUnprocessableEntity)
+
+	var body api.BatchErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Errors) != 1 || body.Errors[0].Index != 2 || body.Errors[0].Errors[0].Field != "email" {
+		t.Errorf("got %+v, want an email error on item 2", body.Errors)
+	}
+	if users := s.ListUsers(""); len(users) != 0 {
+		t.Errorf("got %d users stored, want none", len(users))
+	}
+}
+
+func TestBatchDeleteReportsEachID(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	kept := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	deleted := s.InsertUser(apitest.NewUser("John", "Roe", "john@example.com"))
+	missing := uuid.NewString()
+
+	rec := s.Do(http.MethodPost, "/users/batch-delete", []string{deleted.ID.String(), missing, "not-a-uuid"})
+	apitest.ExpectStatus(t, rec, http.StatusMultiStatus)
+
+	var got api.BatchDeleteResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []api.BatchDeleteResult{
+		{ID: deleted.ID.String(), Status: api.BatchDeleteDeleted},
+		{ID: missing, Status: api.BatchDeleteNotFound},
+		{ID: "not-a-uuid", Status: api.BatchDeleteInvalidID},
+	}
+	if !slices.Equal(got.Results, want) {
+		t.Errorf("got results %v, want %v", got.Results, want)
+	}
+
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/users/"+deleted.ID.String(), nil), http.StatusNotFound)
+	s.GetUser(kept.ID)
+}
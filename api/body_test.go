@@ -0,0 +1,127 @@
+package api_test
+
+import (
+	"io"
+	"net/http"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"strings"
+	"testing"
+)
+
+// rawRequest builds a request whose body is sent exactly as given.
+func rawRequest(s *apitest.Server, method, path, contentType, body string) *http.Request {
+	req := s.NewRequest(method, path, nil)
+	req.Body = io.NopCloser(strings.NewReader(body))
+	req.ContentLength = int64(len(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req
+}
+
+func TestBodyOverLimitIsRejected(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.MaxBodyBytes = 256
+	s := apitest.NewTestServerWithConfig(t, cfg)
+
+	prefix := `{"first_name":"Jane","last_name":"Doe","email":"jane@example.com","biography":"`
+	fill := int(cfg.MaxBodyBytes) - len(prefix) - len(`"}`)
+	atLimit := prefix + strings.Repeat("a", fill) + `"}`
+	overLimit := prefix + strings.Repeat("a", fill+1) + `"}`
+
+	rec := s.Serve(rawRequest(s, http.MethodPost, "/users", "application/json", atLimit))
+	apitest.ExpectStatus(t, rec, http.StatusCreated)
+
+	rec = s.Serve(rawRequest(s, http.MethodPost, "/users", "application/json", overLimit))
+	if got := apitest.DecodeError(t, rec, http.StatusRequestEntityTooLarge); got.Code != api.ErrCodeBodyTooLarge {
+		t.Errorf("got code %q, want %q", got.Code, api.ErrCodeBodyTooLarge)
+	}
+}
+
+// POST /users is the one insert path, so it must do what the removed experimental handler
+// showed: read the body once, refuse fields the model doesn't have and store the result.
+func TestInsertRejectsUnknownFields(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	body := `{"first_name":"Jane","last_name":"Doe","email":"jane@example.com","biography":"Hi","nickname":"JD"}`
+	rec := s.Serve(rawRequest(s, http.MethodPost, "/users", "application/json", body))
+	got := apitest.DecodeError(t, rec, http.StatusBadRequest)
+	if got.Code != api.ErrCodeInvalidBody {
+		t.Errorf("got code %q, want %q", got.Code, api.ErrCodeInvalidBody)
+	}
+	if users := s.ListUsers(""); len(users) != 0 {
+		t.Errorf("got %d users stored, want none", len(users))
+	}
+}
+
+func TestInsertNeedsJSON(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	body := `{"first_name":"Jane","last_name":"Doe","email":"jane@example.com","biography":"Hi"}`
+
+	rec := s.Serve(rawRequest(s, http.MethodPost, "/users", "text/plain", body))
+	if got := apitest.DecodeError(t, rec, http.StatusUnsupportedMediaType); got.Code != api.ErrCodeUnsupportedMediaType {
+		t.Errorf("got code %q, want %q", got.Code, api.ErrCodeUnsupportedMediaType)
+	}
+
+	rec = s.Serve(rawRequest(s, http.MethodPost, "/users", "application/json; charset=utf-8", body))
+	apitest.ExpectStatus(t, rec, http.StatusCreated)
+}
+
+func TestEmptyAndMalformedBodiesAreTold(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	tests := []struct {
+		name, body, message string
+	}{
+		{"empty", "", "Request body is required"},
+		{"truncated", "{", "Malformed JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.Serve(rawRequest(s, http.MethodPost, "/users", "application/json", tt.body))
+			got := apitest.DecodeError(t, rec, http.StatusBadRequest)
+			if got.Code != api.ErrCodeInvalidBody || got.Message != tt.message {
+				t.Errorf("got %q %q, want %q %q", got.Code, got.Message, api.ErrCodeInvalidBody, tt.message)
+			}
+		})
+	}
+}
+
+func TestDuplicateFieldsAreRejected(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	body := `{"first_name":"Jane","last_name":"Doe","email":"jane@example.com","biography":"a","biography":"b"}`
+	rec := s.Serve(rawRequest(s, http.MethodPost, "/users", "application/json", body))
+	got := apitest.DecodeError(t, rec, http.StatusBadRequest)
+	if got.Code != api.ErrCodeInvalidBody || got.Message != "duplicate field: biography" {
+		t.Errorf("got %q %q, want %q \"duplicate field: biography\"", got.Code, got.Message, api.ErrCodeInvalidBody)
+	}
+	if users := s.ListUsers(""); len(users) != 0 {
+		t.Errorf("got %d users stored, want none", len(users))
+	}
+}
+
+func TestMismatchedContentLength(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	body := `{"first_name":"Jane","last_name":"Doe","email":"jane@example.com","biography":"Hi"}`
+
+	for name, declared := range map[string]int64{
+		"shorter than declared": int64(len(body)) + 10,
+		"longer than declared":  int64(len(body)) - 10,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := rawRequest(s, http.MethodPost, "/users", "application/json", body)
+			req.ContentLength = declared
+			got := apitest.DecodeError(t, s.Serve(req), http.StatusBadRequest)
+			if got.Code != api.ErrCodeInvalidBody || !strings.Contains(got.Message, "Content-Length") {
+				t.Errorf("got %q %q, want %q about Content-Length", got.Code, got.Message, api.ErrCodeInvalidBody)
+			}
+		})
+	}
+	if users := s.ListUsers(""); len(users) != 0 {
+		t.Errorf("got %d users stored, want none", len(users))
+	}
+
+	apitest.ExpectStatus(t, s.Serve(rawRequest(s, http.MethodPost, "/users", "application/json", body)), http.StatusCreated)
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+var gzipWriters = sync.Pool{
+	New: func() any { return gzip.NewWriter(nil) },
+}
+
+// gzipWriter holds a response back until it has seen minBytes of it, then gzips it if it grew
+// that big. Shorter responses go out as they are, since compressing them saves next to nothing.
+type gzipWriter struct {
+	http.ResponseWriter
+	minBytes int
+	status   int
+	buf      bytes.Buffer
+	gz       *gzip.Writer
+	// decided is set once the response has been sent on, compressed or not
+	decided bool
+}
+
+func (gw *gzipWriter) WriteHeader(status int) {
+	if gw.status == 0 {
+		gw.status = status
+	}
+}
+
+func (gw *gzipWriter) Write(p []byte) (int, error) {
+	if gw.status == 0 {
+		gw.status = http.StatusOK
+	}
+	if gw.decided {
+		if gw.gz != nil {
+			return gw.gz.Write(p)
+		}
+		return gw.ResponseWriter.Write(p)
+	}
+
+	gw.buf.Write(p)
+	if gw.buf.Len() >= gw.minBytes {
+		if err := gw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// decide sends the headers and anything buffered, gzipped when compress is set and the
+// response is one that can be.
+func (gw *gzipWriter) decide(compress bool) error {
+	gw.decided = true
+
+	header := gw.Header()
+	if gw.status == 0 {
+		gw.status = http.StatusOK
+	}
+	if header.Get("Content-Encoding") != "" || gw.status < 200 || gw.status == http.StatusNoContent || gw.status == http.StatusNotModified {
+		compress = false
+	}
+
+	if compress {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		gw.gz = gzipWriters.Get().(*gzip.Writer)
+		gw.gz.Reset(gw.ResponseWriter)
+	}
+
+	gw.ResponseWriter.WriteHeader(gw.status)
+	if gw.buf.Len() == 0 {
+		return nil
+	}
+
+	var err error
+	if gw.gz != nil {
+		_, err = gw.gz.Write(gw.buf.Bytes())
+	} else {
+		_, err = gw.ResponseWriter.Write(gw.buf.Bytes())
+	}
+	gw.buf.Reset()
+
+	return err
+}
+
+// Flush commits to compressing, since a handler that flushes is streaming and will usually
+// send more than it has so far.
+func (gw *gzipWriter) Flush() {
+	if !gw.decided {
+		gw.decide(true)
+	}
+	if gw.gz != nil {
+		gw.gz.Flush()
+	}
+	if flusher, ok := gw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (gw *gzipWriter) Unwrap() http.ResponseWriter {
+	return gw.ResponseWriter
+}
+
+func (gw *gzipWriter) close() {
+	if !gw.decided {
+		if gw.status == 0 && gw.buf.Len() == 0 {
+			return
+		}
+		gw.decide(false)
+	}
+	if gw.gz != nil {
+		gw.gz.Close()
+		gzipWriters.Put(gw.gz)
+	}
+}
+
+// acceptsGzip reports whether the Accept-Encoding header allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		return strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0"
+	}
+
+	return false
+}
+
+// compress gzips responses of at least minBytes for clients that accept it. Zero disables it.
+func compress(minBytes int) func(http.Handler) http.Handler {
+	if minBytes <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if r.Method == http.MethodHead || !acceptsGzip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			gw := &gzipWriter{ResponseWriter: w, minBytes: minBytes}
+			defer gw.close()
+
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
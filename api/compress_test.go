@@ -0,0 +1,53 @@
+package api_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"testing"
+)
+
+func TestGzipLargeResponses(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	users := insertNamed(s, "Ada", "Grace", "Linus", "Ken", "Dennis", "Barbara", "Margaret", "Edsger", "Donald", "Alan")
+
+	plain := s.Do(http.MethodGet, "/users", nil)
+	apitest.ExpectStatus(t, plain, http.StatusOK)
+	if plain.Body.Len() < api.DefaultConfig().CompressMinBytes {
+		t.Fatalf("got a %d byte list, too small to test compression", plain.Body.Len())
+	}
+	if got := plain.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("got Content-Encoding %q without Accept-Encoding", got)
+	}
+
+	req := s.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := s.Serve(req)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("got Content-Encoding %q, want gzip", got)
+	}
+
+	gz, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, plain.Body.Bytes()) {
+		t.Errorf("got %s after decompressing, want %s", body, plain.Body)
+	}
+
+	req = s.NewRequest(http.MethodGet, "/users/"+users[0].ID.String(), nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = s.Serve(req)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("got Content-Encoding %q for a small response", got)
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+var errContentLengthMismatch = errors.New("request body length doesn't match Content-Length")
+
+// contentLengthBody fails reads with errContentLengthMismatch as soon as the body turns out
+// longer or shorter than declared.
+type contentLengthBody struct {
+	io.ReadCloser
+	declared int64
+	read     int64
+}
+
+func (b *contentLengthBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+
+	switch {
+	case b.read > b.declared:
+		return n, errContentLengthMismatch
+	// net/http reports a body cut short of its Content-Length as io.ErrUnexpectedEOF
+	case errors.Is(err, io.ErrUnexpectedEOF), err == io.EOF && b.read < b.declared:
+		return n, errContentLengthMismatch
+	}
+
+	return n, err
+}
+
+// checkContentLength makes bodies that disagree with their Content-Length fail to decode, so
+// they're answered 400 instead of being taken for malformed JSON. net/http never hands over
+// more than the declared length, treating the rest as the next request, but the check also
+// covers handlers served some other way. Bodies of unknown length are left alone.
+func checkContentLength(enabled bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !enabled {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength >= 0 && r.Body != nil && r.Body != http.NoBody {
+				r.Body = &contentLengthBody{ReadCloser: r.Body, declared: r.ContentLength}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CORSConfig struct {
+	// AllowedOrigins lists the origins browsers may call from; "*" allows any. Empty disables CORS.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// AllowCredentials lets browsers send cookies and read responses to requests that carry
+	// them. It can't be combined with a "*" origin.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight answer. Zero leaves it to the browser.
+	MaxAge time.Duration
+}
+
+// Validate reports settings browsers would refuse or that would be unsafe. Allowing credentials
+// from any origin would let every site make authenticated calls on a visitor's behalf.
+func (c CORSConfig) Validate() error {
+	if c.AllowCredentials && slices.Contains(c.AllowedOrigins, "*") {
+		return errors.New("credentials can't be allowed from any origin, list the origins instead")
+	}
+	if c.MaxAge < 0 {
+		return errors.New("max age must not be negative")
+	}
+
+	return nil
+}
+
+func (c CORSConfig) allows(origin string) bool {
+	return slices.Contains(c.AllowedOrigins, "*") || slices.Contains(c.AllowedOrigins, origin)
+}
+
+// corsMiddleware answers preflight requests itself and adds the CORS headers to every other
+// request from an allowed origin. Requests from other origins pass through without them, so
+// the browser blocks the response. It panics on a cfg that doesn't pass Validate, since that's
+// a mistake in the code setting up the handler.
+func corsMiddleware(cfg CORSConfig) func(http.Handler) http.Handler {
+	if err := cfg.Validate(); err != nil {
+		panic("api: CORS: " + err.Error())
+	}
+
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	maxAge := ""
+	if cfg.MaxAge > 0 {
+		maxAge = strconv.Itoa(int(cfg.MaxAge / time.Second))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" || !cfg.allows(origin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Origin")
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if cfg.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if !preflight {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+			if maxAge != "" {
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
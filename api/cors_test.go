@@ -0,0 +1,112 @@
+package api_test
+
+import (
+	"net/http"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newCORSServer(t *testing.T, cors func(*api.CORSConfig)) *apitest.Server {
+	cfg := api.DefaultConfig()
+	cfg.CORS.AllowedOrigins = []string{"https://app.example.com"}
+	if cors != nil {
+		cors(&cfg.CORS)
+	}
+	return apitest.NewTestServerWithConfig(t, cfg)
+}
+
+func TestCORSPreflight(t *testing.T) {
+	s := newCORSServer(t, nil)
+
+	req := s.NewRequest(http.MethodOptions, "/users", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec := s.Serve(req)
+
+	apitest.ExpectStatus(t, rec, http.StatusNoContent)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("got Access-Control-Allow-Origin %q, want the origin", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(got, http.MethodPost) {
+		t.Errorf("got Access-Control-Allow-Methods %q, want POST among them", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(got, "Content-Type") {
+		t.Errorf("got Access-Control-Allow-Headers %q, want Content-Type among them", got)
+	}
+}
+
+func TestCORSPreflightOutsideTheUsersRoutes(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.CORS.AllowedOrigins = []string{"https://app.example.com"}
+	cfg.AllowAdmin = true
+	s := apitest.NewTestServerWithConfig(t, cfg)
+
+	// these routes take a single method, unlike the mounted users routes
+	for _, path := range []string{"/openapi.json", "/admin/export", "/admin/maintenance"} {
+		req := s.NewRequest(http.MethodOptions, path, nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		rec := s.Serve(req)
+
+		apitest.ExpectStatus(t, rec, http.StatusNoContent)
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+			t.Errorf("got Access-Control-Allow-Origin %q for %s, want the origin", got, path)
+		}
+	}
+}
+
+func TestCORSOnCrossOriginGet(t *testing.T) {
+	s := newCORSServer(t, nil)
+
+	req := s.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec := s.Serve(req)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("got Access-Control-Allow-Origin %q, want the origin", got)
+	}
+
+	req = s.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec = s.Serve(req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("got Access-Control-Allow-Origin %q for an unlisted origin, want none", got)
+	}
+}
+
+func TestCORSMaxAgeAndCredentials(t *testing.T) {
+	s := newCORSServer(t, func(cors *api.CORSConfig) {
+		cors.MaxAge = 10 * time.Minute
+		cors.AllowCredentials = true
+	})
+
+	req := s.NewRequest(http.MethodOptions, "/users", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec := s.Serve(req)
+
+	apitest.ExpectStatus(t, rec, http.StatusNoContent)
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("got Access-Control-Max-Age %q, want 600", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("got Access-Control-Allow-Credentials %q, want true", got)
+	}
+}
+
+func TestCORSRefusesWildcardWithCredentials(t *testing.T) {
+	cors := api.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+	if err := cors.Validate(); err == nil {
+		t.Error("got no error validating a wildcard origin with credentials")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("got a handler for a wildcard origin with credentials, want a panic")
+		}
+	}()
+	newCORSServer(t, func(c *api.CORSConfig) { *c = cors })
+}
@@ -0,0 +1,50 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"testing"
+)
+
+func TestDebugVars(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.DebugVars = true
+	s := apitest.NewTestServerWithConfig(t, cfg)
+	jane := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	s.InsertUser(apitest.NewUser("John", "Roe", "john@example.com"))
+	s.DeleteUser(jane.ID)
+
+	rec := s.Do(http.MethodGet, "/debug/vars", nil)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	var vars map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil {
+		t.Fatalf("got %s, want JSON: %v", rec.Body, err)
+	}
+
+	if got := string(vars["total_users"]); got != "1" {
+		t.Errorf("got total_users %s, want 1", got)
+	}
+	var served int
+	if err := json.Unmarshal(vars["requests_served"], &served); err != nil || served < 3 {
+		t.Errorf("got requests_served %s, want at least the 3 requests made", vars["requests_served"])
+	}
+	if vars["memstats"] == nil {
+		t.Error("got no memstats, want Go's own vars kept")
+	}
+
+	apitest.ExpectStatus(t, apitest.NewTestServer(t).Do(http.MethodGet, "/debug/vars", nil), http.StatusNotFound)
+}
+
+func TestPprof(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.Pprof = true
+	s := apitest.NewTestServerWithConfig(t, cfg)
+
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/debug/pprof/", nil), http.StatusOK)
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil), http.StatusOK)
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/users", nil), http.StatusOK)
+
+	apitest.ExpectStatus(t, apitest.NewTestServer(t).Do(http.MethodGet, "/debug/pprof/", nil), http.StatusNotFound)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// handleDebugVars answers like expvar.Handler, adding requests_served and total_users. They
+// aren't published to expvar, since that registry is global and every handler has its own.
+func handleDebugVars[T any](m *metrics, res *Resource[T]) http.HandlerFunc {
+	total := "total_" + strings.ToLower(res.name) + "s"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		totalVar := expvar.Func(func() any {
+			count, err := res.countLive(r.Context())
+			if err != nil {
+				res.logger.ErrorContext(r.Context(), "failed to count records for /debug/vars", "error", err)
+				return nil
+			}
+			return count
+		})
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, "{\n")
+		first := true
+		write := func(kv expvar.KeyValue) {
+			if !first {
+				fmt.Fprint(w, ",\n")
+			}
+			first = false
+			key, _ := json.Marshal(kv.Key)
+			fmt.Fprintf(w, "%s: %s", key, kv.Value)
+		}
+		expvar.Do(write)
+		write(expvar.KeyValue{Key: "requests_served", Value: &m.served})
+		write(expvar.KeyValue{Key: total, Value: totalVar})
+		fmt.Fprint(w, "\n}\n")
+	}
+}
+
+// countLive returns how many records aren't soft-deleted.
+func (res *Resource[T]) countLive(ctx context.Context) (int, error) {
+	entries, err := res.list(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, entry := range entries {
+		if !isDeleted(entry.Value) {
+			count++
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// dryRun reports whether a write should only be checked, not stored, which clients ask for
+// with ?dry_run=true or a Prefer: dry-run header.
+func dryRun(r *http.Request) (bool, error) {
+	if raw := r.URL.Query().Get("dry_run"); raw != "" {
+		dry, err := strconv.ParseBool(raw)
+		if err != nil {
+			return false, errors.New("dry_run must be a boolean")
+		}
+		return dry, nil
+	}
+
+	for _, header := range r.Header.Values("Prefer") {
+		for _, preference := range strings.Split(header, ",") {
+			token, _, _ := strings.Cut(preference, ";")
+			if strings.EqualFold(strings.TrimSpace(token), "dry-run") {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// writeDryRun answers a dry run with what the write would have stored. It's a 200 whatever the
+// write would have answered, since nothing was created or changed.
+func writeDryRun(w http.ResponseWriter, r *http.Request, v any) {
+	w.Header().Set("Preference-Applied", "dry-run")
+	writeJSON(w, r, http.StatusOK, v)
+}
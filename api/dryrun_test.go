@@ -0,0 +1,43 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"rocketseat/api/apitest"
+	"testing"
+)
+
+func TestDryRunInsertStoresNothing(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	rec := s.Do(http.MethodPost, "/users?dry_run=true", apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	var wouldBe apitest.User
+	if err := json.Unmarshal(rec.Body.Bytes(), &wouldBe); err != nil {
+		t.Fatal(err)
+	}
+	if *wouldBe.Email != "jane@example.com" || wouldBe.CreatedAt.IsZero() {
+		t.Errorf("got %+v, want the user as it would be stored", wouldBe.User)
+	}
+
+	req := s.NewRequest(http.MethodPost, "/users", apitest.NewUser("John", "Roe", "john@example.com"))
+	req.Header.Set("Prefer", "dry-run")
+	rec = s.Serve(req)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Preference-Applied"); got != "dry-run" {
+		t.Errorf("got Preference-Applied %q, want dry-run", got)
+	}
+
+	if users := s.ListUsers(""); len(users) != 0 {
+		t.Errorf("got %d users after dry runs, want none", len(users))
+	}
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/users/"+wouldBe.ID.String(), nil), http.StatusNotFound)
+}
+
+func TestDryRunStillValidates(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := apitest.NewUser("Jane", "Doe", "not-an-email")
+
+	rec := s.Do(http.MethodPost, "/users?dry_run=true", user)
+	apitest.ExpectStatus(t, rec, http.StatusUnprocessableEntity)
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// eventBuffer is how many events a subscriber may fall behind by before it misses some.
+	eventBuffer = 16
+	// eventKeepAlive is how often an idle stream gets a comment, so proxies don't close it.
+	eventKeepAlive = 15 * time.Second
+)
+
+// sseFrame is one event, encoded once for every subscriber.
+type sseFrame struct {
+	event string
+	data  []byte
+}
+
+// EventStream is an EventHook fanning changes out to the clients of GET /events.
+type EventStream[T any] struct {
+	mu          sync.Mutex
+	subscribers map[chan sseFrame]struct{}
+}
+
+func NewEventStream[T any]() *EventStream[T] {
+	return &EventStream[T]{subscribers: map[chan sseFrame]struct{}{}}
+}
+
+func (s *EventStream[T]) OnCreate(_ context.Context, record Response[T]) error {
+	return s.publish(EventCreate, record)
+}
+
+func (s *EventStream[T]) OnUpdate(_ context.Context, record Response[T]) error {
+	return s.publish(EventUpdate, record)
+}
+
+func (s *EventStream[T]) OnDelete(_ context.Context, record Response[T]) error {
+	return s.publish(EventDelete, record)
+}
+
+// publish hands the event to every subscriber without waiting on any. One that's too far
+// behind misses it, which is reported as the error.
+func (s *EventStream[T]) publish(event string, record Response[T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.subscribers) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(newEvent(event, record))
+	if err != nil {
+		return err
+	}
+
+	dropped := 0
+	for ch := range s.subscribers {
+		select {
+		case ch <- sseFrame{event: event, data: data}:
+		default:
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		return fmt.Errorf("%d of %d event stream subscribers fell behind and missed the event", dropped, len(s.subscribers))
+	}
+
+	return nil
+}
+
+// subscribe returns a channel receiving every event from now on, and the func that stops it.
+func (s *EventStream[T]) subscribe() (<-chan sseFrame, func()) {
+	ch := make(chan sseFrame, eventBuffer)
+
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}
+}
+
+// handleEvents streams every change to the resource as Server-Sent Events until the client
+// disconnects. Events that happened before the client connected aren't replayed.
+func (res *Resource[T]) handleEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		// the server's write timeout is meant for ordinary responses, not one held open for good
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			res.logger.WarnContext(r.Context(), "event stream is subject to the write timeout", "error", err)
+		}
+
+		events, unsubscribe := res.events.subscribe()
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		if err := rc.Flush(); err != nil {
+			res.logger.ErrorContext(r.Context(), "event stream can't be flushed", "error", err)
+			return
+		}
+
+		keepAlive := time.NewTicker(eventKeepAlive)
+		defer keepAlive.Stop()
+
+		for {
+			var err error
+			select {
+			case <-r.Context().Done():
+				return
+			case frame := <-events:
+				_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", frame.event, frame.data)
+			case <-keepAlive.C:
+				_, err = fmt.Fprint(w, ": keep-alive\n\n")
+			}
+			if err == nil {
+				err = rc.Flush()
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package api_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"rocketseat/models"
+	"strings"
+	"testing"
+)
+
+func TestEventStreamSendsCreate(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	server := httptest.NewServer(s.Handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/users/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("got Content-Type %q, want text/event-stream", got)
+	}
+
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+
+	lines := bufio.NewScanner(resp.Body)
+	var frame []string
+	for lines.Scan() && lines.Text() != "" {
+		frame = append(frame, lines.Text())
+	}
+	if len(frame) != 2 || frame[0] != "event: "+api.EventCreate || !strings.HasPrefix(frame[1], "data: ") {
+		t.Fatalf("got frame %q, want a create event with data", frame)
+	}
+
+	var event api.Event[*models.User]
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(frame[1], "data: ")), &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != api.EventCreate || event.ID != user.ID || *event.Payload.Email != "jane@example.com" {
+		t.Errorf("got %+v, want the create of %s", event, user.ID)
+	}
+}
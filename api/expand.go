@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"rocketseat/models"
+	"slices"
+	"strings"
+)
+
+// Expander loads the object related to record under some relation, for ?expand= to nest in it.
+// A nil result or models.ErrNotFound means the record has none, which is sent as null.
+type Expander[T any] func(ctx context.Context, record Response[T]) (any, error)
+
+// relation is an expanded relation, in the order the client asked for it.
+type relation struct {
+	name  string
+	value any
+}
+
+// WithRelation lets GET /{id}?expand=name nest what expand loads in the record under name.
+// Without the parameter the record is sent as stored, so it only holds whatever reference to
+// the related object the model keeps itself. It panics on names the record already uses for
+// something else, since that's a mistake in the code setting up the routes.
+func (res *Resource[T]) WithRelation(name string, expand Expander[T]) *Resource[T] {
+	if name == "id" || name == "deleted" || slices.Contains(jsonFieldNames(reflect.TypeFor[T]()), name) {
+		panic(fmt.Sprintf("api: WithRelation: %q is already a field of %s", name, res.name))
+	}
+
+	if res.relations == nil {
+		res.relations = map[string]Expander[T]{}
+	}
+	res.relations[name] = expand
+	return res
+}
+
+// relationNames returns the names WithRelation registered, sorted.
+func (res *Resource[T]) relationNames() []string {
+	names := make([]string, 0, len(res.relations))
+	for name := range res.relations {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// parseExpand reads the comma-separated relations of the expand query parameter.
+func (res *Resource[T]) parseExpand(r *http.Request) ([]string, error) {
+	raw := r.URL.Query().Get("expand")
+	if raw == "" {
+		return nil, nil
+	}
+
+	var names []string
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := res.relations[name]; !ok {
+			return nil, fmt.Errorf("cannot expand %q", name)
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+// expand loads the relations named in names into resp.
+func (res *Resource[T]) expand(ctx context.Context, resp *Response[T], names []string) error {
+	for _, name := range names {
+		value, err := res.relations[name](ctx, *resp)
+		if errors.Is(err, models.ErrNotFound) {
+			value, err = nil, nil
+		}
+		if err != nil {
+			return fmt.Errorf("expanding %s: %w", name, err)
+		}
+		resp.expanded = append(resp.expanded, relation{name: name, value: value})
+	}
+
+	return nil
+}
+
+// appendRelations adds every relation to the end of a JSON object.
+func appendRelations(object []byte, relations []relation) ([]byte, error) {
+	object = bytes.TrimSpace(object)
+
+	var buf bytes.Buffer
+	buf.Write(object[:len(object)-1])
+	empty := len(bytes.TrimSpace(object[1:len(object)-1])) == 0
+	for _, rel := range relations {
+		value, err := json.Marshal(rel.value)
+		if err != nil {
+			return nil, fmt.Errorf("expanding %s: %w", rel.name, err)
+		}
+
+		if !empty {
+			buf.WriteByte(',')
+		}
+		empty = false
+		name, _ := json.Marshal(rel.name)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
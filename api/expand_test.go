@@ -0,0 +1,54 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"rocketseat/models"
+	"testing"
+)
+
+type address struct {
+	City string `json:"city"`
+}
+
+func TestExpandNestsRelations(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.Relations = map[string]api.Expander[*models.User]{
+		"address": func(_ context.Context, record api.Response[*models.User]) (any, error) {
+			return address{City: "Lisbon"}, nil
+		},
+	}
+	s := apitest.NewTestServerWithConfig(t, cfg)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	path := "/users/" + user.ID.String()
+
+	get := func(query string) map[string]json.RawMessage {
+		rec := s.Do(http.MethodGet, path+query, nil)
+		apitest.ExpectStatus(t, rec, http.StatusOK)
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+
+	if plain := get(""); plain["address"] != nil {
+		t.Errorf("got address %s without expand, want none", plain["address"])
+	}
+
+	expanded := get("?expand=address")
+	if got := string(expanded["address"]); got != `{"city":"Lisbon"}` {
+		t.Errorf("got address %s, want Lisbon", got)
+	}
+	if got := string(expanded["email"]); got != `"jane@example.com"` {
+		t.Errorf("got email %s, want the user's fields kept", got)
+	}
+
+	rec := s.Do(http.MethodGet, path+"?expand=employer", nil)
+	if got := apitest.DecodeError(t, rec, http.StatusBadRequest); got.Code != api.ErrCodeInvalidQuery {
+		t.Errorf("got code %q, want %q", got.Code, api.ErrCodeInvalidQuery)
+	}
+}
@@ -0,0 +1,73 @@
+package api_test
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"rocketseat/api/apitest"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestExportCSV(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	jane := apitest.NewUser("Jane", "Doe", "jane@example.com")
+	jane.Biography = ptr("Writes, \"sometimes\"\nand reads")
+	created := s.InsertUser(jane)
+	s.InsertUser(apitest.NewUser("John", "Roe", "john@example.com"))
+
+	rec := s.Do(http.MethodGet, "/users.csv?sort=first_name", nil)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("got Content-Type %q, want text/csv", got)
+	}
+
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want a header and 2 users", len(rows))
+	}
+
+	header := rows[0]
+	column := func(name string) int {
+		i := slices.Index(header, name)
+		if i < 0 {
+			t.Fatalf("header %v has no %s", header, name)
+		}
+		return i
+	}
+	first := rows[1]
+	if first[column("id")] != created.ID.String() || first[column("first_name")] != "Jane" || first[column("biography")] != *jane.Biography {
+		t.Errorf("got row %q, want Jane's fields", first)
+	}
+	if rows[2][column("email")] != "john@example.com" {
+		t.Errorf("got row %q, want John's", rows[2])
+	}
+}
+
+func TestExportCSVSpansChunks(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	// enough users for several flushes, with a partial chunk at the end
+	const count = 250
+	for i := range count {
+		user := apitest.NewUser("Jane", "Doe", fmt.Sprintf("jane%d@example.com", i))
+		if err := s.Store.Insert(context.Background(), uuid.New(), &user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := s.Do(http.MethodGet, "/users.csv", nil)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != count+1 {
+		t.Errorf("got %d rows, want a header and %d users", len(rows), count)
+	}
+}
@@ -0,0 +1,113 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"rocketseat/models"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type note struct {
+	Title *string `json:"title"`
+}
+
+func TestTwoResourcesOnOneMux(t *testing.T) {
+	r := chi.NewMux()
+	api.NewResource[*models.User](models.NewStore[*models.User]()).RegisterRoutes(r, "/users")
+	api.NewResource[*note](models.NewStore[*note]()).RegisterRoutes(r, "/notes")
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, newJSONRequest(t, http.MethodPost, "/notes", note{Title: ptr("Groceries")}))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("got status %d creating a note; body: %s", rec.Code, rec.Body)
+	}
+	var created struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/notes/"+created.ID, nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("got status %d fetching the note", rec.Code)
+	}
+
+	// each resource has a store of its own
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/"+created.ID, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("got status %d fetching the note's ID as a user, want 404", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, newJSONRequest(t, http.MethodPost, "/notes", note{}))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("got status %d for a note without a title, want 422", rec.Code)
+	}
+}
+
+// unencodable is a record that can be stored but never written back out.
+type unencodable struct {
+	Title *string `json:"title"`
+}
+
+func (*unencodable) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("not today")
+}
+
+func TestMarshalFailureIsACleanError(t *testing.T) {
+	store := models.NewStore[*unencodable]()
+	id := uuid.New()
+	if err := store.Insert(context.Background(), id, &unencodable{Title: ptr("Broken")}); err != nil {
+		t.Fatal(err)
+	}
+	r := chi.NewMux()
+	api.NewResource[*unencodable](store).WithLogger(discardLogger()).RegisterRoutes(r, "/things")
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/things/"+id.String(), nil))
+
+	got := apitest.DecodeError(t, rec, http.StatusInternalServerError)
+	if got.Code != api.ErrCodeInternal {
+		t.Errorf("got code %q, want %q", got.Code, api.ErrCodeInternal)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("got Content-Type %q, want JSON", ct)
+	}
+}
+
+func TestExportEncodeFailureIsACleanError(t *testing.T) {
+	store := models.NewStore[*unencodable]()
+	if err := store.Insert(context.Background(), uuid.New(), &unencodable{Title: ptr("Broken")}); err != nil {
+		t.Fatal(err)
+	}
+	r := chi.NewMux()
+	api.NewResource[*unencodable](store).WithLogger(discardLogger()).RegisterRoutes(r, "/things")
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/things.csv", nil))
+
+	// decoding the body as a JSON error also shows no CSV was written ahead of it
+	got := apitest.DecodeError(t, rec, http.StatusInternalServerError)
+	if got.Code != api.ErrCodeInternal {
+		t.Errorf("got code %q, want %q", got.Code, api.ErrCodeInternal)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("got Content-Type %q, want JSON", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); cd != "" {
+		t.Errorf("got Content-Disposition %q on an error", cd)
+	}
+}
@@ -0,0 +1,50 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"rocketseat/api"
+	"rocketseat/models"
+	"sync/atomic"
+	"testing"
+)
+
+// flakyStore is a store whose Ping fails until up is set.
+type flakyStore struct {
+	*models.Store[*models.User]
+	up atomic.Bool
+}
+
+func (s *flakyStore) Ping(context.Context) error {
+	if !s.up.Load() {
+		return errors.New("database unreachable")
+	}
+	return nil
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	store := &flakyStore{Store: models.NewStore[*models.User]()}
+	cfg := api.DefaultConfig()
+	cfg.Logger = discardLogger()
+	handler := api.NewHandler(store, cfg)
+
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	if got := get("/healthz"); got != http.StatusOK {
+		t.Errorf("got healthz %d, want 200", got)
+	}
+	if got := get("/readyz"); got != http.StatusServiceUnavailable {
+		t.Errorf("got readyz %d while the store is down, want 503", got)
+	}
+
+	store.up.Store(true)
+	if got := get("/readyz"); got != http.StatusOK {
+		t.Errorf("got readyz %d once the store is up, want 200", got)
+	}
+}
@@ -0,0 +1,34 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// newJSONRequest builds a request with body encoded as JSON, for tests that call a handler
+// other than an apitest.Server's.
+func newJSONRequest(t testing.TB, method, path string, body any) *http.Request {
+	t.Helper()
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("encoding %s %s body: %v", method, path, err)
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(encoded))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
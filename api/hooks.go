@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// EventHook is told about every record the API creates, updates or deletes, once the store
+// has accepted the change. The record is passed the way the API answers with it. A returned
+// error is logged, but the request still succeeds since the change has already been made.
+type EventHook[T any] interface {
+	OnCreate(ctx context.Context, record Response[T]) error
+	OnUpdate(ctx context.Context, record Response[T]) error
+	OnDelete(ctx context.Context, record Response[T]) error
+}
+
+// UpdateDiffHook is an EventHook that also wants to know what an update replaced. Its
+// OnUpdateFrom is called instead of OnUpdate, with the record before and after the update.
+type UpdateDiffHook[T any] interface {
+	OnUpdateFrom(ctx context.Context, before, after Response[T]) error
+}
+
+// Event is how a change is sent to webhooks and event streams.
+type Event[T any] struct {
+	Type    string    `json:"type"`
+	ID      uuid.UUID `json:"id"`
+	Payload T         `json:"payload"`
+	// idFormat is how ID is written, taken from the record the event is about.
+	idFormat string
+}
+
+// newEvent builds the event for record, writing its ID the way the record's response does.
+func newEvent[T any](event string, record Response[T]) Event[T] {
+	return Event[T]{Type: event, ID: record.ID, Payload: record.Model, idFormat: record.idFormat}
+}
+
+func (e Event[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type    string `json:"type"`
+		ID      string `json:"id"`
+		Payload T      `json:"payload"`
+	}{e.Type, formatID(e.idFormat, e.ID), e.Payload})
+}
+
+// NopHook ignores every event.
+type NopHook[T any] struct{}
+
+func (NopHook[T]) OnCreate(context.Context, Response[T]) error { return nil }
+func (NopHook[T]) OnUpdate(context.Context, Response[T]) error { return nil }
+func (NopHook[T]) OnDelete(context.Context, Response[T]) error { return nil }
+
+const (
+	EventCreate = "create"
+	EventUpdate = "update"
+	EventDelete = "delete"
+)
+
+// WithHook makes the resource also report its changes to hook, after its own event stream.
+func (res *Resource[T]) WithHook(hook EventHook[T]) *Resource[T] {
+	res.hooks = append(res.hooks, hook)
+	return res
+}
+
+// emit reports event on record to every hook in turn, logging rather than returning failures.
+func (res *Resource[T]) emit(ctx context.Context, event string, record Response[T]) {
+	record.idFormat = res.idFormat
+	for _, hook := range res.hooks {
+		var err error
+		switch event {
+		case EventCreate:
+			err = hook.OnCreate(ctx, record)
+		case EventUpdate:
+			err = hook.OnUpdate(ctx, record)
+		case EventDelete:
+			err = hook.OnDelete(ctx, record)
+		}
+		if err != nil {
+			res.logger.ErrorContext(ctx, "event hook failed", "event", event, "id", record.ID, "error", err)
+		}
+	}
+}
+
+// emitUpdate reports the update of before to record, passing before on to the hooks that want it.
+func (res *Resource[T]) emitUpdate(ctx context.Context, before T, record Response[T]) {
+	record.idFormat = res.idFormat
+	for _, hook := range res.hooks {
+		var err error
+		if differ, ok := hook.(UpdateDiffHook[T]); ok {
+			err = differ.OnUpdateFrom(ctx, res.response(record.ID, before), record)
+		} else {
+			err = hook.OnUpdate(ctx, record)
+		}
+		if err != nil {
+			res.logger.ErrorContext(ctx, "event hook failed", "event", EventUpdate, "id", record.ID, "error", err)
+		}
+	}
+}
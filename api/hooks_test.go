@@ -0,0 +1,60 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"rocketseat/models"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// recordingHook keeps the operation and ID of every event, and fails each one when err is set.
+type recordingHook struct {
+	mu     sync.Mutex
+	events [][2]string
+	err    error
+}
+
+func (h *recordingHook) record(op string, record api.Response[*models.User]) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, [2]string{op, record.ID.String()})
+	return h.err
+}
+
+func (h *recordingHook) OnCreate(_ context.Context, record api.Response[*models.User]) error {
+	return h.record(api.EventCreate, record)
+}
+
+func (h *recordingHook) OnUpdate(_ context.Context, record api.Response[*models.User]) error {
+	return h.record(api.EventUpdate, record)
+}
+
+func (h *recordingHook) OnDelete(_ context.Context, record api.Response[*models.User]) error {
+	return h.record(api.EventDelete, record)
+}
+
+func TestHookHearsEachOperation(t *testing.T) {
+	for name, hookErr := range map[string]error{"succeeding": nil, "failing": errors.New("hook down")} {
+		t.Run(name, func(t *testing.T) {
+			hook := &recordingHook{err: hookErr}
+			cfg := api.DefaultConfig()
+			cfg.EventHook = hook
+			s := apitest.NewTestServerWithConfig(t, cfg)
+
+			user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+			user.FirstName = ptr("Janet")
+			s.UpdateUser(user.ID, user.User)
+			s.DeleteUser(user.ID)
+
+			id := user.ID.String()
+			want := [][2]string{{api.EventCreate, id}, {api.EventUpdate, id}, {api.EventDelete, id}}
+			if !slices.Equal(hook.events, want) {
+				t.Errorf("got events %v, want %v", hook.events, want)
+			}
+		})
+	}
+}
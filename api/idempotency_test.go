@@ -0,0 +1,82 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rocketseat/api/apitest"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// insertWithKey posts user under the Idempotency-Key key.
+func insertWithKey(s *apitest.Server, key uuid.UUID, first string) *httptest.ResponseRecorder {
+	req := s.NewRequest(http.MethodPost, "/users", apitest.NewUser(first, "Doe", "jane@example.com"))
+	req.Header.Set("Idempotency-Key", key.String())
+	return s.Serve(req)
+}
+
+func TestRepeatedInsertAnswersWithTheFirst(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	key := uuid.New()
+
+	rec := insertWithKey(s, key, "Jane")
+	apitest.ExpectStatus(t, rec, http.StatusCreated)
+	var created apitest.User
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != key {
+		t.Errorf("got ID %s, want the key %s", created.ID, key)
+	}
+
+	// a retry with a changed body still gets the record the first request made
+	rec = insertWithKey(s, key, "Janet")
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	var repeated apitest.User
+	if err := json.Unmarshal(rec.Body.Bytes(), &repeated); err != nil {
+		t.Fatal(err)
+	}
+	if repeated.ID != key || *repeated.FirstName != "Jane" {
+		t.Errorf("got %s named %s, want the first insert", repeated.ID, *repeated.FirstName)
+	}
+	if users := s.ListUsers(""); len(users) != 1 {
+		t.Errorf("got %d users stored, want 1", len(users))
+	}
+}
+
+func TestConcurrentIdempotentInsertsStoreOneUser(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	key := uuid.New()
+
+	const n = 20
+	statuses := make([]int, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = insertWithKey(s, key, "Jane").Code
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, status := range statuses {
+		switch status {
+		case http.StatusCreated:
+			created++
+		case http.StatusOK:
+		default:
+			t.Errorf("got status %d, want 201 or 200", status)
+		}
+	}
+	if created != 1 {
+		t.Errorf("got %d inserts answered 201, want 1", created)
+	}
+	if users := s.ListUsers(""); len(users) != 1 || users[0].ID != key {
+		t.Errorf("got %d users stored, want just the one under the key", len(users))
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/hex"
+
+	"github.com/google/uuid"
+)
+
+// IDGenerator picks the IDs of records created without one from the client.
+type IDGenerator interface {
+	New() uuid.UUID
+}
+
+// UUIDv4 generates random IDs. It's the default.
+type UUIDv4 struct{}
+
+func (UUIDv4) New() uuid.UUID {
+	return uuid.New()
+}
+
+// UUIDv7 generates IDs that start with a timestamp, so they sort in creation order and new
+// records land next to each other in ordered storage.
+type UUIDv7 struct{}
+
+func (UUIDv7) New() uuid.UUID {
+	return uuid.Must(uuid.NewV7())
+}
+
+// Formats IDs can be written in. Requests may use any of them whatever the setting, since
+// uuid.Parse reads them all.
+const (
+	// IDFormatCanonical is the hyphenated form, like 6ba7b810-9dad-11d1-80b4-00c04fd430c8.
+	IDFormatCanonical = "canonical"
+	// IDFormatNoHyphens is the same 32 hex digits without hyphens.
+	IDFormatNoHyphens = "no-hyphens"
+	// IDFormatURN is the RFC 9562 URN, like urn:uuid:6ba7b810-9dad-11d1-80b4-00c04fd430c8.
+	IDFormatURN = "urn"
+)
+
+// formatID writes id in format, falling back to the canonical form for an unknown one.
+func formatID(format string, id uuid.UUID) string {
+	switch format {
+	case IDFormatNoHyphens:
+		return hex.EncodeToString(id[:])
+	case IDFormatURN:
+		return id.URN()
+	default:
+		return id.String()
+	}
+}
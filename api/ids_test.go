@@ -0,0 +1,135 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"rocketseat/models"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestIDFormatAppliesToResponsesListingsAndEvents(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer receiver.Close()
+
+	webhook := api.NewWebhook[*models.User](api.WebhookConfig{URL: receiver.URL}, discardLogger())
+	cfg := api.DefaultConfig()
+	cfg.IDFormat = api.IDFormatNoHyphens
+	cfg.EventHook = webhook
+	s := apitest.NewTestServerWithConfig(t, cfg)
+
+	rec := s.Do(http.MethodPost, "/users", apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	apitest.ExpectStatus(t, rec, http.StatusCreated)
+	var created map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	id, _ := created["id"].(string)
+	if len(id) != 32 || strings.Contains(id, "-") {
+		t.Fatalf("got id %q, want 32 hex digits", id)
+	}
+	if location := rec.Header().Get("Location"); !strings.HasSuffix(location, "/"+id) {
+		t.Errorf("got Location %q, want it to end in the id", location)
+	}
+
+	rec = s.Do(http.MethodGet, "/users?format=ids", nil)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	var ids []string
+	if err := json.Unmarshal(rec.Body.Bytes(), &ids); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != id {
+		t.Errorf("got ids %q, want [%q]", ids, id)
+	}
+
+	if err := webhook.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var event map[string]any
+	if err := json.Unmarshal(<-bodies, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event["id"] != id {
+		t.Errorf("got event id %v, want %q", event["id"], id)
+	}
+}
+
+// fixedIDs hands out the IDs it was given, in order.
+type fixedIDs []uuid.UUID
+
+func (ids *fixedIDs) New() uuid.UUID {
+	id := (*ids)[0]
+	*ids = (*ids)[1:]
+	return id
+}
+
+func TestInsertUsesIDGenerator(t *testing.T) {
+	want := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	cfg := api.DefaultConfig()
+	cfg.IDGenerator = &fixedIDs{want}
+	s := apitest.NewTestServerWithConfig(t, cfg)
+
+	if got := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com")).ID; got != want {
+		t.Errorf("got ID %s, want %s", got, want)
+	}
+}
+
+func TestIDFormatsRoundTrip(t *testing.T) {
+	tests := []struct {
+		format string
+		shape  func(id uuid.UUID) string
+	}{
+		{api.IDFormatCanonical, uuid.UUID.String},
+		{api.IDFormatNoHyphens, func(id uuid.UUID) string { return strings.ReplaceAll(id.String(), "-", "") }},
+		{api.IDFormatURN, uuid.UUID.URN},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			cfg := api.DefaultConfig()
+			cfg.IDFormat = tt.format
+			s := apitest.NewTestServerWithConfig(t, cfg)
+
+			rec := s.Do(http.MethodPost, "/users", apitest.NewUser("Jane", "Doe", "jane@example.com"))
+			apitest.ExpectStatus(t, rec, http.StatusCreated)
+			var created struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+				t.Fatal(err)
+			}
+			id, err := uuid.Parse(created.ID)
+			if err != nil {
+				t.Fatalf("got id %q, want a UUID: %v", created.ID, err)
+			}
+			if created.ID != tt.shape(id) {
+				t.Errorf("got id %q, want %q", created.ID, tt.shape(id))
+			}
+
+			// IDs are accepted in any of the formats, whichever one responses use
+			for _, other := range tests {
+				rec := s.Do(http.MethodGet, "/users/"+other.shape(id), nil)
+				apitest.ExpectStatus(t, rec, http.StatusOK)
+				var got struct {
+					ID string `json:"id"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+					t.Fatal(err)
+				}
+				if got.ID != created.ID {
+					t.Errorf("got id %q fetching by %s, want %q", got.ID, other.format, created.ID)
+				}
+			}
+		})
+	}
+}
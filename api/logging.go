@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+type loggerKey struct{}
+
+// withLogger makes logger the one helpers without a handler of their own, like writeJSON, log to.
+func withLogger(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), loggerKey{}, logger)))
+		})
+	}
+}
+
+// loggerFrom returns the logger withLogger put in ctx, or slog.Default() outside NewHandler.
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// requestLogger logs one structured line per request once the handler has finished,
+// so the status and size are the ones the client actually received. Requests slower than
+// slowThreshold are logged as warnings instead; zero never warns.
+func requestLogger(logger *slog.Logger, slowThreshold time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r)
+
+			// handlers that never call WriteHeader implicitly answer 200
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			duration := time.Since(start)
+			level, message := slog.LevelInfo, "request completed"
+			if slowThreshold > 0 && duration > slowThreshold {
+				level, message = slog.LevelWarn, "slow request"
+			}
+
+			logger.Log(r.Context(), level, message,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", status,
+				"bytes", ww.BytesWritten(),
+				"duration", duration,
+			)
+		})
+	}
+}
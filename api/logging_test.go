@@ -0,0 +1,147 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"rocketseat/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// logEntry returns the first JSON line in logs with message msg, failing the test without one.
+func logEntry(t *testing.T, logs *bytes.Buffer, msg string) map[string]any {
+	t.Helper()
+
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		if entry["msg"] == msg {
+			return entry
+		}
+	}
+	t.Fatalf("no %q line in %s", msg, logs.String())
+	return nil
+}
+
+func newLoggedServer(t *testing.T, cfg api.Config) (*apitest.Server, *bytes.Buffer) {
+	var logs bytes.Buffer
+	cfg.Logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	return apitest.NewTestServerWithConfig(t, cfg), &logs
+}
+
+func TestRequestLineFields(t *testing.T) {
+	s, logs := newLoggedServer(t, api.DefaultConfig())
+
+	path := "/users/" + uuid.NewString()
+	rec := s.Do(http.MethodGet, path, nil)
+	entry := logEntry(t, logs, "request completed")
+
+	if entry["level"] != "INFO" || entry["method"] != "GET" || entry["path"] != path {
+		t.Errorf("got %v, want an INFO line for the GET", entry)
+	}
+	if entry["status"] != float64(http.StatusNotFound) || entry["bytes"] != float64(rec.Body.Len()) {
+		t.Errorf("got status %v and bytes %v, want 404 and %d", entry["status"], entry["bytes"], rec.Body.Len())
+	}
+	if _, ok := entry["duration"].(float64); !ok {
+		t.Errorf("got duration %v, want a number", entry["duration"])
+	}
+}
+
+func TestRequestLineCarriesRequestID(t *testing.T) {
+	for name, wrap := range map[string]bool{"plain logger": false, "ContextHandler logger": true} {
+		t.Run(name, func(t *testing.T) {
+			var logs bytes.Buffer
+			var handler slog.Handler = slog.NewJSONHandler(&logs, nil)
+			if wrap {
+				handler = api.ContextHandler{Handler: handler}
+			}
+			cfg := api.DefaultConfig()
+			cfg.Logger = slog.New(handler)
+			s := apitest.NewTestServerWithConfig(t, cfg)
+
+			req := s.NewRequest(http.MethodGet, "/users", nil)
+			req.Header.Set("X-Request-ID", "req-123")
+			apitest.ExpectStatus(t, s.Serve(req), http.StatusOK)
+
+			for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+				if !strings.Contains(line, `"request completed"`) {
+					continue
+				}
+				var entry map[string]any
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					t.Fatal(err)
+				}
+				if entry["request_id"] != "req-123" {
+					t.Errorf("got request_id %v, want req-123", entry["request_id"])
+				}
+				if n := strings.Count(line, `"request_id"`); n != 1 {
+					t.Errorf("got request_id %d times in %s", n, line)
+				}
+				return
+			}
+			t.Fatalf("no request line in %s", logs.String())
+		})
+	}
+}
+
+func TestSlowRequestIsAWarning(t *testing.T) {
+	var logs bytes.Buffer
+	cfg := api.DefaultConfig()
+	cfg.Logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	cfg.SlowRequestThreshold = 50 * time.Millisecond
+	handler := api.NewHandler(stuckStore{models.NewStore[*models.User]()}, cfg)
+
+	path := "/users/" + uuid.NewString()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	slow := logEntry(t, &logs, "slow request")
+	if slow["level"] != "WARN" || slow["method"] != http.MethodGet || slow["path"] != path {
+		t.Errorf("got %v, want a warning for GET %s", slow, path)
+	}
+	if duration, _ := slow["duration"].(float64); time.Duration(duration) < cfg.SlowRequestThreshold {
+		t.Errorf("got duration %v, want it over the threshold", slow["duration"])
+	}
+
+	fast := logEntry(t, &logs, "request completed")
+	if fast["level"] != "INFO" || fast["path"] != "/users" {
+		t.Errorf("got %v, want an info line for /users", fast)
+	}
+}
+
+// brokenStore is a store whose reads always fail.
+type brokenStore struct {
+	*models.Store[*models.User]
+}
+
+func (brokenStore) Get(context.Context, uuid.UUID) (*models.User, error) {
+	return nil, errors.New("disk on fire")
+}
+
+func TestInjectedLoggerCapturesErrors(t *testing.T) {
+	var logs bytes.Buffer
+	cfg := api.DefaultConfig()
+	cfg.Logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	handler := api.NewHandler(brokenStore{models.NewStore[*models.User]()}, cfg)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/"+uuid.NewString(), nil))
+	apitest.ExpectStatus(t, rec, http.StatusInternalServerError)
+
+	entry := logEntry(t, &logs, "storage error")
+	if entry["level"] != "ERROR" || entry["error"] != "disk on fire" {
+		t.Errorf("got %v, want the store's error at error level", entry)
+	}
+}
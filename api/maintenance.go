@@ -0,0 +1,84 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const defaultMaintenanceRetryAfter = time.Minute
+
+// Maintenance is a switch taking the API offline. While it's on, every route but /healthz and
+// the admin endpoint that turns it off answers 503 with a Retry-After header.
+type Maintenance struct {
+	on         atomic.Bool
+	retryAfter time.Duration
+}
+
+// NewMaintenance returns a switch that starts off, telling clients to come back after retryAfter
+// once it's on. Zero means a minute.
+func NewMaintenance(retryAfter time.Duration) *Maintenance {
+	if retryAfter <= 0 {
+		retryAfter = defaultMaintenanceRetryAfter
+	}
+
+	return &Maintenance{retryAfter: retryAfter}
+}
+
+func (m *Maintenance) Enabled() bool { return m.on.Load() }
+
+func (m *Maintenance) Set(on bool) { m.on.Store(on) }
+
+// Toggle flips the switch, reporting whether it's now on.
+func (m *Maintenance) Toggle() bool {
+	for {
+		on := m.on.Load()
+		if m.on.CompareAndSwap(on, !on) {
+			return !on
+		}
+	}
+}
+
+// middleware answers 503 to everything while the switch is on, except the paths in exempt.
+// Trailing slashes are ignored, since the router takes /healthz/ for /healthz too.
+func (m *Maintenance) middleware(exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !m.Enabled() || slices.Contains(exempt, trimTrailingSlashes(r.URL.Path)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(m.retryAfter.Seconds()))))
+			writeError(w, http.StatusServiceUnavailable, ErrCodeMaintenance, "The API is down for maintenance, try again later")
+		})
+	}
+}
+
+// trimTrailingSlashes strips the slashes path ends in, leaving "/" alone.
+func trimTrailingSlashes(path string) string {
+	if trimmed := strings.TrimRight(path, "/"); trimmed != "" {
+		return trimmed
+	}
+	return path
+}
+
+type MaintenanceResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+// handleMaintenance reports the switch on GET, turns it on with PUT and off with DELETE.
+func (m *Maintenance) handleMaintenance() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			m.Set(r.Method == http.MethodPut)
+			loggerFrom(r.Context()).InfoContext(r.Context(), "maintenance mode switched", "enabled", m.Enabled())
+		}
+
+		writeJSON(w, r, http.StatusOK, MaintenanceResponse{Enabled: m.Enabled()})
+	}
+}
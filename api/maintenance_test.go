@@ -0,0 +1,37 @@
+package api_test
+
+import (
+	"net/http"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	maintenance := api.NewMaintenance(2 * time.Minute)
+	cfg := api.DefaultConfig()
+	cfg.Maintenance = maintenance
+	cfg.AllowAdmin = true
+	s := apitest.NewTestServerWithConfig(t, cfg)
+
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/users", nil), http.StatusOK)
+
+	apitest.ExpectStatus(t, s.Do(http.MethodPut, "/admin/maintenance", nil), http.StatusOK)
+	if !maintenance.Enabled() {
+		t.Fatal("got maintenance off after switching it on")
+	}
+
+	rec := s.Do(http.MethodGet, "/users", nil)
+	if got := apitest.DecodeError(t, rec, http.StatusServiceUnavailable); got.Code != api.ErrCodeMaintenance {
+		t.Errorf("got code %q, want %q", got.Code, api.ErrCodeMaintenance)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "120" {
+		t.Errorf("got Retry-After %q, want 120", got)
+	}
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/healthz", nil), http.StatusOK)
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/healthz/", nil), http.StatusOK)
+
+	apitest.ExpectStatus(t, s.Do(http.MethodDelete, "/admin/maintenance", nil), http.StatusOK)
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/users", nil), http.StatusOK)
+}
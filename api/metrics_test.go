@@ -0,0 +1,46 @@
+package api_test
+
+import (
+	"bufio"
+	"net/http"
+	"rocketseat/api/apitest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// scrape returns the value of the series starting with series in /metrics, or "" without one.
+func scrape(t *testing.T, s *apitest.Server, series string) string {
+	t.Helper()
+
+	rec := s.Do(http.MethodGet, "/metrics", nil)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), series+" "); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func TestMetricsCountRequests(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	series := `http_requests_total{method="GET",path="/users/{id}",status="404"}`
+
+	if got := scrape(t, s, series); got != "" {
+		t.Fatalf("got %s before any request, want no series", got)
+	}
+
+	s.Do(http.MethodGet, "/users/"+uuid.NewString(), nil)
+	if got := scrape(t, s, series); got != "1" {
+		t.Errorf("got %q after one request, want 1", got)
+	}
+
+	s.Do(http.MethodGet, "/users/"+uuid.NewString(), nil)
+	if got := scrape(t, s, series); got != "2" {
+		t.Errorf("got %q after two requests, want 2", got)
+	}
+}
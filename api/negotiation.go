@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	contentTypeJSON    = "application/json"
+	contentTypeMsgpack = "application/x-msgpack"
+)
+
+// wantsMsgpack reports whether the Accept header prefers MessagePack over JSON. Anything else,
+// including a missing or unknown Accept header, gets JSON.
+func wantsMsgpack(r *http.Request) bool {
+	header := r.Header.Get("Accept")
+	if header == "" {
+		return false
+	}
+
+	jsonQ, msgpackQ := 0.0, 0.0
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case contentTypeMsgpack:
+			msgpackQ = max(msgpackQ, q)
+		case contentTypeJSON, "application/*", "*/*":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+
+	return msgpackQ > 0 && msgpackQ > jsonQ
+}
+
+// encodeResponse marshals v in the format the client asked for, returning the body and its
+// content type. Responses are always built as JSON first, so both formats share field names
+// and the ID splicing done by Response.
+func encodeResponse(r *http.Request, v any) ([]byte, string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !wantsMsgpack(r) {
+		return data, contentTypeJSON, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic any
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, "", err
+	}
+
+	data, err = msgpack.Marshal(msgpackNumbers(generic))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, contentTypeMsgpack, nil
+}
+
+// msgpackNumbers swaps the json.Numbers in a decoded JSON value for ints or floats, which
+// msgpack would otherwise encode as strings.
+func msgpackNumbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, value := range v {
+			v[key] = msgpackNumbers(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = msgpackNumbers(value)
+		}
+	}
+
+	return v
+}
+
+// errUnsupportedMediaType is returned by requestBody for bodies that are neither JSON nor MessagePack.
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
+// requestBody returns the request body as JSON, converting a MessagePack body when its
+// Content-Type says so, so every handler decodes with the same rules either way. A missing
+// Content-Type is taken to mean JSON.
+func requestBody(r *http.Request) (io.Reader, error) {
+	header := r.Header.Get("Content-Type")
+	if header == "" {
+		return r.Body, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return nil, errUnsupportedMediaType
+	}
+
+	switch mediaType {
+	case contentTypeJSON:
+		return r.Body, nil
+	case contentTypeMsgpack:
+	default:
+		return nil, errUnsupportedMediaType
+	}
+
+	content, err := io.ReadAll(r.Body)
+	if err != nil || len(content) == 0 {
+		return bytes.NewReader(content), err
+	}
+
+	var generic any
+	if err := msgpack.Unmarshal(content, &generic); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(generic)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(data), nil
+}
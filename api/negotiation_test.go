@@ -0,0 +1,62 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"rocketseat/api/apitest"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestRoundTripThroughMessagePack(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	body, err := msgpack.Marshal(map[string]any{
+		"first_name": "Jane", "last_name": "Doe", "biography": "Packed", "email": "jane@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := s.NewRequest(http.MethodPost, "/users", nil)
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", "application/x-msgpack")
+	req.Header.Set("Accept", "application/x-msgpack")
+	rec := s.Serve(req)
+
+	apitest.ExpectStatus(t, rec, http.StatusCreated)
+	if got := rec.Header().Get("Content-Type"); got != "application/x-msgpack" {
+		t.Fatalf("got Content-Type %q, want application/x-msgpack", got)
+	}
+	var created struct {
+		FirstName string `msgpack:"first_name"`
+		Biography string `msgpack:"biography"`
+		Version   int    `msgpack:"version"`
+	}
+	if err := msgpack.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.FirstName != "Jane" || created.Biography != "Packed" || created.Version != 1 {
+		t.Errorf("got %+v, want the user sent at version 1", created)
+	}
+}
+
+func TestRoundTripThroughJSON(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	rec := s.Do(http.MethodPost, "/users", apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	apitest.ExpectStatus(t, rec, http.StatusCreated)
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("got Content-Type %q, want application/json", got)
+	}
+	var created apitest.User
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.GetUser(created.ID); *got.FirstName != "Jane" || *got.Email != "jane@example.com" {
+		t.Errorf("got %+v, want the user sent", got.User)
+	}
+}
@@ -0,0 +1,434 @@
+package api
+
+import (
+	"net/http"
+	"rocketseat/models"
+	"strings"
+)
+
+// openAPIDescriber is implemented by everything that adds paths to the OpenAPI document.
+type openAPIDescriber interface {
+	describeOpenAPI(paths, schemas map[string]any)
+}
+
+func errorResponse(description string) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Error"}},
+		},
+	}
+}
+
+func jsonResponse(description string, schema map[string]any) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content":     map[string]any{"application/json": map[string]any{"schema": schema}},
+	}
+}
+
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// newOpenAPIDocument builds the OpenAPI 3.0 description of the API. The shared schemas are
+// written out here, while each resource describes its own paths and model.
+func newOpenAPIDocument(describers ...openAPIDescriber) map[string]any {
+	paths := map[string]any{}
+	schemas := map[string]any{
+		"Error": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"error": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"code":     map[string]any{"type": "string"},
+						"message":  map[string]any{"type": "string"},
+						"resource": map[string]any{"type": "string", "description": "Set on not_found errors"},
+						"id":       map[string]any{"type": "string", "description": "Set on not_found errors"},
+					},
+				},
+			},
+		},
+		"ValidationErrors": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"errors": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"field":   map[string]any{"type": "string"},
+							"message": map[string]any{"type": "string"},
+							"limit":   map[string]any{"type": "integer", "description": "The length limit that was broken"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, describer := range describers {
+		describer.describeOpenAPI(paths, schemas)
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "go-crud",
+			"version": "1.0.0",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	}
+}
+
+func handleOpenAPI(describers ...openAPIDescriber) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, r, http.StatusOK, newOpenAPIDocument(describers...))
+	}
+}
+
+func (res *Resource[T]) describeOpenAPI(paths, schemas map[string]any) {
+	name := res.name
+	schemas[name] = modelSchema[T]()
+	properties, _ := schemas[name].(map[string]any)["properties"].(map[string]any)
+	for field, n := range res.maxLengths {
+		if property, ok := properties[field].(map[string]any); ok {
+			property["maxLength"] = n
+		}
+	}
+	schemas[name+"List"] = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"data": map[string]any{"type": "array", "items": ref(name)},
+			"meta": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"total":  map[string]any{"type": "integer"},
+					"limit":  map[string]any{"type": "integer"},
+					"offset": map[string]any{"type": "integer"},
+				},
+			},
+		},
+	}
+	if res.bareLists {
+		schemas[name+"List"] = map[string]any{"type": "array", "items": ref(name)}
+	}
+
+	tag := strings.ToLower(name) + "s"
+	query := func(param, schemaType, description string) map[string]any {
+		return map[string]any{"name": param, "in": "query", "description": description, "schema": map[string]any{"type": schemaType}}
+	}
+	idParam := map[string]any{"name": "id", "in": "path", "required": true, "schema": map[string]any{"type": "string", "format": "uuid"}}
+	fieldsParam := query("fields", "string", "Comma-separated fields to return, instead of all of them")
+	limitParam := query("limit", "integer", "Page size")
+	if res.maxLimit > 0 {
+		limitParam["schema"].(map[string]any)["maximum"] = res.maxLimit
+	}
+	sortParam := query("sort", "string", "Field to sort by, prefixed with - for descending order")
+	if res.defaultSort.field != "" {
+		sortParam["schema"].(map[string]any)["default"] = res.defaultSort.String()
+	}
+	listParams := []any{
+		limitParam,
+		query("offset", "integer", "Number of records to skip"),
+		sortParam,
+		query("include_deleted", "boolean", "Include soft-deleted records"),
+		query("format", "string", "Set to ids for a flat array of every matching ID instead of a page of records"),
+		fieldsParam,
+	}
+	body := map[string]any{
+		"required": true,
+		"content":  map[string]any{"application/json": map[string]any{"schema": ref(name)}},
+	}
+
+	patchBody := map[string]any{
+		"required": true,
+		"content": map[string]any{
+			"application/json":    map[string]any{"schema": ref(name)},
+			contentTypeMergePatch: map[string]any{"schema": map[string]any{"type": "object"}},
+			contentTypeJSONPatch: map[string]any{"schema": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type":     "object",
+					"required": []string{"op", "path"},
+					"properties": map[string]any{
+						"op":    map[string]any{"type": "string", "enum": []string{"add", "remove", "replace", "test"}},
+						"path":  map[string]any{"type": "string"},
+						"value": map[string]any{},
+					},
+				},
+			}},
+		},
+	}
+
+	common := map[string]any{
+		"400": errorResponse("Malformed request"),
+		"401": errorResponse("Missing or invalid bearer token"),
+		"429": errorResponse("Rate limit exceeded"),
+		"500": errorResponse("Internal error"),
+		"503": errorResponse("Request timed out, or the API is down for maintenance"),
+	}
+	responses := func(extra map[string]any) map[string]any {
+		merged := map[string]any{}
+		for code, response := range common {
+			merged[code] = response
+		}
+		for code, response := range extra {
+			merged[code] = response
+		}
+		return merged
+	}
+	writeResponses := map[string]any{
+		"404": errorResponse(name + " not found"),
+		"409": errorResponse("Conflicts with another record or a newer version"),
+		"413": errorResponse("Request body too large"),
+		"415": errorResponse("Content-Type is neither JSON nor MessagePack"),
+		"422": jsonResponse("Validation failed", ref("ValidationErrors")),
+	}
+
+	paths[res.prefix] = map[string]any{
+		"get": map[string]any{
+			"tags":       []string{tag},
+			"summary":    "List " + tag,
+			"parameters": listParams,
+			"responses":  responses(map[string]any{"200": jsonResponse("A page of "+tag, ref(name+"List"))}),
+		},
+		"head": map[string]any{
+			"tags":       []string{tag},
+			"summary":    "Count " + tag + " without fetching them",
+			"parameters": listParams,
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "The number of matching " + tag + " is in X-Total-Count",
+					"headers":     map[string]any{"X-Total-Count": map[string]any{"schema": map[string]any{"type": "integer"}}},
+				},
+				"400": map[string]any{"description": "Invalid query"},
+			},
+		},
+		"post": map[string]any{
+			"tags":        []string{tag},
+			"summary":     "Create a " + strings.ToLower(name),
+			"requestBody": body,
+			"parameters": []any{map[string]any{
+				"name": "Idempotency-Key", "in": "header", "schema": map[string]any{"type": "string", "format": "uuid"},
+				"description": "ID for the new record, so retries return it instead of creating another",
+			}},
+			"responses": responses(map[string]any{
+				"200": jsonResponse("Already created by an earlier request with the same ID", ref(name)),
+				"201": jsonResponse("Created", ref(name)),
+				"409": writeResponses["409"],
+				"413": writeResponses["413"],
+				"415": writeResponses["415"],
+				"422": writeResponses["422"],
+				"507": errorResponse("The store holds as many records as it's allowed to"),
+			}),
+		},
+	}
+	if _, ok := res.db.(models.Expirer); ok {
+		post := paths[res.prefix].(map[string]any)["post"].(map[string]any)
+		post["parameters"] = append(post["parameters"].([]any), query("ttl", "string", "How long until the record expires, like 10m"))
+	}
+	if res.allowClear {
+		paths[res.prefix].(map[string]any)["delete"] = map[string]any{
+			"tags":      []string{tag},
+			"summary":   "Permanently delete every " + strings.ToLower(name),
+			"responses": responses(map[string]any{"204": map[string]any{"description": "Deleted"}}),
+		}
+	}
+	var zero T
+	if _, ok := any(zero).(SoftDeletable); ok {
+		properties["deleted"] = map[string]any{
+			"type":        "boolean",
+			"readOnly":    true,
+			"description": "Set only on soft-deleted records, which listings return with include_deleted",
+		}
+		paths[res.prefix+"/{id}/restore"] = map[string]any{
+			"parameters": []any{idParam},
+			"post": map[string]any{
+				"tags":    []string{tag},
+				"summary": "Restore a deleted " + strings.ToLower(name),
+				"responses": responses(map[string]any{
+					"200": jsonResponse("Restored", ref(name)),
+					"404": errorResponse("No deleted " + strings.ToLower(name) + " with this id"),
+					"409": writeResponses["409"],
+				}),
+			},
+		}
+	}
+	paths[res.prefix+".csv"] = map[string]any{
+		"get": map[string]any{
+			"tags":    []string{tag},
+			"summary": "Export " + tag + " as CSV",
+			"responses": responses(map[string]any{"200": map[string]any{
+				"description": "One row per " + strings.ToLower(name) + ", after a header row",
+				"content":     map[string]any{"text/csv": map[string]any{"schema": map[string]any{"type": "string"}}},
+			}}),
+		},
+	}
+	paths[res.prefix+"/search"] = map[string]any{
+		"get": map[string]any{
+			"tags":       []string{tag},
+			"summary":    "Search " + tag + " by free text",
+			"parameters": append([]any{query("q", "string", "Text to look for")}, listParams...),
+			"responses":  responses(map[string]any{"200": jsonResponse("A page of matching "+tag, ref(name+"List"))}),
+		},
+	}
+	paths[res.prefix+"/count"] = map[string]any{
+		"get": map[string]any{
+			"tags":    []string{tag},
+			"summary": "Count " + tag,
+			"responses": responses(map[string]any{"200": jsonResponse("Number of matching "+tag, map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"count": map[string]any{"type": "integer"}},
+			})}),
+		},
+	}
+	paths[res.prefix+"/schema"] = map[string]any{
+		"get": map[string]any{
+			"tags":    []string{tag},
+			"summary": "Get the JSON Schema of a " + strings.ToLower(name),
+			"responses": responses(map[string]any{
+				"200": jsonResponse("JSON Schema document", map[string]any{"type": "object"}),
+				"304": map[string]any{"description": "Not modified since the ETag in If-None-Match"},
+			}),
+		},
+	}
+	paths[res.prefix+"/events"] = map[string]any{
+		"get": map[string]any{
+			"tags":        []string{tag},
+			"summary":     "Stream changes to " + tag,
+			"description": "Server-Sent Events named create, update or delete, each holding the type, id and payload of the change as JSON. Only changes made after connecting are sent.",
+			"responses": responses(map[string]any{"200": map[string]any{
+				"description": "Stream of changes",
+				"content":     map[string]any{"text/event-stream": map[string]any{"schema": map[string]any{"type": "string"}}},
+			}}),
+		},
+	}
+	paths[res.prefix+"/batch"] = map[string]any{
+		"post": map[string]any{
+			"tags":    []string{tag},
+			"summary": "Create several " + tag + " at once, or none if any is invalid",
+			"requestBody": map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": map[string]any{"type": "array", "items": ref(name)}}},
+			},
+			"responses": responses(map[string]any{
+				"201": jsonResponse("Created", map[string]any{"type": "array", "items": ref(name)}),
+				"413": writeResponses["413"],
+				"415": writeResponses["415"],
+				"422": errorResponse("Validation failed for some items"),
+			}),
+		},
+	}
+	paths[res.prefix+"/batch-delete"] = map[string]any{
+		"post": map[string]any{
+			"tags":    []string{tag},
+			"summary": "Delete several " + tag + " at once, reporting the outcome for each",
+			"requestBody": map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": map[string]any{"type": "array", "items": map[string]any{"type": "string", "format": "uuid"}}}},
+			},
+			"responses": responses(map[string]any{
+				"207": jsonResponse("Outcome per ID", map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"results": map[string]any{
+							"type": "array",
+							"items": map[string]any{
+								"type": "object",
+								"properties": map[string]any{
+									"id":     map[string]any{"type": "string"},
+									"status": map[string]any{"type": "string", "enum": []string{BatchDeleteDeleted, BatchDeleteNotFound, BatchDeleteInvalidID, BatchDeleteFailed}},
+								},
+							},
+						},
+					},
+				}),
+				"413": writeResponses["413"],
+				"415": writeResponses["415"],
+			}),
+		},
+	}
+	deleteResponses := map[string]any{
+		"204": map[string]any{"description": "Deleted"},
+		"404": writeResponses["404"],
+		"412": errorResponse("Doesn't match If-Match, or modified after If-Unmodified-Since"),
+	}
+	if res.idempotentDelete {
+		deleteResponses["204"] = map[string]any{"description": "Deleted, or there was no such " + strings.ToLower(name)}
+		delete(deleteResponses, "404")
+	}
+	getParams := []any{fieldsParam}
+	if len(res.relations) > 0 {
+		getParams = append(getParams, query("expand", "string", "Comma-separated relations to nest in the record: "+strings.Join(res.relationNames(), ", ")))
+	}
+	paths[res.prefix+"/{id}"] = map[string]any{
+		"parameters": []any{idParam},
+		"get": map[string]any{
+			"tags":       []string{tag},
+			"summary":    "Get a " + strings.ToLower(name),
+			"parameters": getParams,
+			"responses": responses(map[string]any{
+				"200": jsonResponse("Found", ref(name)),
+				"304": map[string]any{"description": "Not modified since the ETag in If-None-Match"},
+				"404": writeResponses["404"],
+			}),
+		},
+		"head": map[string]any{
+			"tags":    []string{tag},
+			"summary": "Check a " + strings.ToLower(name) + " exists",
+			"responses": map[string]any{
+				"200": map[string]any{"description": "Exists"},
+				"404": map[string]any{"description": "Not found"},
+			},
+		},
+		"put": map[string]any{
+			"tags":        []string{tag},
+			"summary":     "Replace a " + strings.ToLower(name),
+			"requestBody": body,
+			"responses": responses(map[string]any{
+				"200": jsonResponse("Replaced", ref(name)),
+				"201": jsonResponse("Created at this ID, when PUT may create", ref(name)),
+				"404": writeResponses["404"],
+				"409": writeResponses["409"],
+				"413": writeResponses["413"],
+				"415": writeResponses["415"],
+				"422": writeResponses["422"],
+				"412": errorResponse("Doesn't match If-Match, or modified after If-Unmodified-Since"),
+				"428": errorResponse("No version given in If-Match, If-Unmodified-Since or the body"),
+			}),
+		},
+		"patch": map[string]any{
+			"tags":        []string{tag},
+			"summary":     "Update some fields of a " + strings.ToLower(name),
+			"requestBody": patchBody,
+			"responses": responses(map[string]any{
+				"200": jsonResponse("Updated", ref(name)),
+				"404": writeResponses["404"],
+				"409": writeResponses["409"],
+				"413": writeResponses["413"],
+				"415": writeResponses["415"],
+				"422": writeResponses["422"],
+				"412": errorResponse("Doesn't match If-Match"),
+			}),
+		},
+		"delete": map[string]any{
+			"tags":      []string{tag},
+			"summary":   "Delete a " + strings.ToLower(name),
+			"responses": responses(deleteResponses),
+		},
+	}
+
+	dryRunParam := query("dry_run", "boolean", "Check the write and answer 200 with what would be stored, without storing it. Prefer: dry-run does the same")
+	for _, op := range []map[string]any{
+		paths[res.prefix].(map[string]any)["post"].(map[string]any),
+		paths[res.prefix+"/batch"].(map[string]any)["post"].(map[string]any),
+		paths[res.prefix+"/{id}"].(map[string]any)["put"].(map[string]any),
+		paths[res.prefix+"/{id}"].(map[string]any)["patch"].(map[string]any),
+	} {
+		params, _ := op["parameters"].([]any)
+		op["parameters"] = append(params, dryRunParam)
+	}
+}
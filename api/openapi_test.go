@@ -0,0 +1,38 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"rocketseat/api/apitest"
+	"testing"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	rec := s.Do(http.MethodGet, "/openapi.json", nil)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("spec isn't valid JSON: %v", err)
+	}
+	if spec.OpenAPI != "3.0.3" {
+		t.Errorf("got openapi %q, want 3.0.3", spec.OpenAPI)
+	}
+
+	want := map[string][]string{
+		"/users":      {"get", "post"},
+		"/users/{id}": {"get", "put", "patch", "delete"},
+	}
+	for path, methods := range want {
+		for _, method := range methods {
+			if _, ok := spec.Paths[path][method]; !ok {
+				t.Errorf("spec has no %s %s", method, path)
+			}
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"rocketseat/models"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	contentTypeMergePatch = "application/merge-patch+json"
+	contentTypeJSONPatch  = "application/json-patch+json"
+)
+
+// patchFormat returns the media type of a PATCH body, to tell patch documents from plain JSON.
+func patchFormat(r *http.Request) string {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType
+}
+
+// mergePatch applies patch to target as RFC 7396 describes: objects merge key by key, a null
+// removes the key, and anything else replaces the target outright.
+func mergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+
+	return targetObject
+}
+
+// decodeJSONValue decodes a single JSON value, keeping numbers as json.Number so they survive
+// being encoded again unchanged.
+func decodeJSONValue(body io.Reader) (any, error) {
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDuplicateKeys(content); err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// readMergePatch decodes a merge patch body, which must be an object, taking out any "id" key
+// so it can be checked against the URL instead of merged.
+func readMergePatch(body io.Reader) (map[string]any, string, error) {
+	value, err := decodeJSONValue(body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	patch, ok := value.(map[string]any)
+	if !ok {
+		return nil, "", errors.New("merge patch must be a JSON object")
+	}
+
+	var id string
+	if rawID, ok := patch["id"]; ok {
+		if id, ok = rawID.(string); !ok {
+			return nil, "", errors.New("id must be a string")
+		}
+		delete(patch, "id")
+	}
+
+	return patch, id, nil
+}
+
+// applyJSONDocument runs edit on current's JSON form and decodes the result into a new T with
+// the same strict rules as any other body.
+func applyJSONDocument[T any](current T, edit func(doc any) (any, error)) (T, error) {
+	var value T
+
+	encoded, err := json.Marshal(current)
+	if err != nil {
+		return value, err
+	}
+	doc, err := decodeJSONValue(bytes.NewReader(encoded))
+	if err != nil {
+		return value, err
+	}
+
+	if doc, err = edit(doc); err != nil {
+		return value, err
+	}
+
+	if encoded, err = json.Marshal(doc); err != nil {
+		return value, err
+	}
+
+	return decodeBody[T](bytes.NewReader(encoded))
+}
+
+// keepServerFields puts back the fields clients don't get to change through a patch document.
+func keepServerFields(value, current any) {
+	if stamped, ok := value.(Timestamped); ok {
+		stamped.SetCreatedAt(current.(Timestamped).GetCreatedAt())
+	}
+	clearDeleted(value)
+}
+
+// handleMergePatch updates a record from an RFC 7396 merge patch, where keys set to null are
+// removed and keys left out stay as they are.
+func (res *Resource[T]) handleMergePatch(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	patch, bodyID, err := readMergePatch(r.Body)
+	if err != nil {
+		writePatchError(w, r, err)
+		return
+	}
+	if !bodyIDMatches(w, bodyID, id) {
+		return
+	}
+
+	current, ok := res.getLive(w, r, id)
+	if !ok {
+		return
+	}
+
+	value, err := applyJSONDocument(current, func(doc any) (any, error) {
+		return mergePatch(doc, patch), nil
+	})
+	if err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	keepServerFields(value, current)
+
+	// the version only differs from the stored one if the patch sets it
+	if !res.checkVersion(w, r, id, value, current, false) {
+		return
+	}
+
+	res.savePatched(w, r, id, current, value)
+}
+
+// writePatchError reports a patch document that couldn't be read, passing decode errors on to
+// writeDecodeError and describing structural problems with the document itself.
+func writePatchError(w http.ResponseWriter, r *http.Request, err error) {
+	var syntaxErr *json.SyntaxError
+	var tooLarge *http.MaxBytesError
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &syntaxErr) || errors.As(err, &tooLarge) {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	writeError(w, http.StatusBadRequest, ErrCodeInvalidBody, err.Error())
+}
+
+// jsonPatchOp is one operation of an RFC 6902 JSON Patch.
+type jsonPatchOp struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// jsonPatchError is an operation that can't be applied to the record, as opposed to a patch
+// document that can't be read at all.
+type jsonPatchError struct {
+	path    string
+	message string
+}
+
+func (e *jsonPatchError) Error() string {
+	return e.path + ": " + e.message
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into its unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, &jsonPatchError{path: pointer, message: "must be empty or start with /"}
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// applyPatchOp applies op at tokens below node, returning the updated node. Supported operations
+// are add, remove, replace and test.
+func applyPatchOp(node any, tokens []string, op jsonPatchOp, value any) (any, error) {
+	if len(tokens) == 0 {
+		switch op.Op {
+		case "add", "replace":
+			return value, nil
+		case "test":
+			if !reflect.DeepEqual(node, value) {
+				return nil, &jsonPatchError{path: op.Path, message: "test failed"}
+			}
+			return node, nil
+		default:
+			return nil, &jsonPatchError{path: op.Path, message: "cannot remove the whole document"}
+		}
+	}
+
+	token, rest := tokens[0], tokens[1:]
+	switch container := node.(type) {
+	case map[string]any:
+		child, exists := container[token]
+		if len(rest) > 0 {
+			if !exists {
+				return nil, &jsonPatchError{path: op.Path, message: "path does not exist"}
+			}
+			updated, err := applyPatchOp(child, rest, op, value)
+			if err != nil {
+				return nil, err
+			}
+			container[token] = updated
+			return container, nil
+		}
+
+		if !exists && op.Op != "add" {
+			return nil, &jsonPatchError{path: op.Path, message: "path does not exist"}
+		}
+		switch op.Op {
+		case "add", "replace":
+			container[token] = value
+		case "remove":
+			delete(container, token)
+		case "test":
+			if !reflect.DeepEqual(child, value) {
+				return nil, &jsonPatchError{path: op.Path, message: "test failed"}
+			}
+		}
+		return container, nil
+
+	case []any:
+		if token == "-" && len(rest) == 0 && op.Op == "add" {
+			return append(container, value), nil
+		}
+
+		index, err := strconv.Atoi(token)
+		limit := len(container)
+		if op.Op == "add" && len(rest) == 0 {
+			limit++
+		}
+		if err != nil || index < 0 || index >= limit || (token != "0" && strings.HasPrefix(token, "0")) {
+			return nil, &jsonPatchError{path: op.Path, message: "path does not exist"}
+		}
+
+		if len(rest) > 0 {
+			updated, err := applyPatchOp(container[index], rest, op, value)
+			if err != nil {
+				return nil, err
+			}
+			container[index] = updated
+			return container, nil
+		}
+
+		switch op.Op {
+		case "add":
+			return slices.Insert(container, index, value), nil
+		case "replace":
+			container[index] = value
+		case "remove":
+			return slices.Delete(container, index, index+1), nil
+		case "test":
+			if !reflect.DeepEqual(container[index], value) {
+				return nil, &jsonPatchError{path: op.Path, message: "test failed"}
+			}
+		}
+		return container, nil
+
+	default:
+		return nil, &jsonPatchError{path: op.Path, message: "path does not exist"}
+	}
+}
+
+// applyJSONPatch runs every operation against doc in order, stopping at the first that fails.
+// Top-level paths must name one of T's fields, so a patch can't add fields the model lacks.
+func applyJSONPatch[T any](doc any, ops []jsonPatchOp) (any, error) {
+	known := jsonFieldNames(reflect.TypeFor[T]())
+
+	for _, op := range ops {
+		switch op.Op {
+		case "add", "remove", "replace", "test":
+		default:
+			return nil, &jsonPatchError{path: op.Path, message: fmt.Sprintf("unsupported op %q", op.Op)}
+		}
+
+		tokens, err := parsePointer(op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if len(tokens) > 0 && !slices.Contains(known, tokens[0]) {
+			return nil, &jsonPatchError{path: op.Path, message: "unknown field"}
+		}
+
+		var value any
+		if op.Op != "remove" {
+			if op.Value == nil {
+				return nil, &jsonPatchError{path: op.Path, message: fmt.Sprintf("%s needs a value", op.Op)}
+			}
+			if value, err = decodeJSONValue(bytes.NewReader(*op.Value)); err != nil {
+				return nil, err
+			}
+		}
+
+		if doc, err = applyPatchOp(doc, tokens, op, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return doc, nil
+}
+
+// handleJSONPatch updates a record from an RFC 6902 JSON Patch. Operations that can't be
+// applied, like removing a path that doesn't exist, fail the whole patch with a 422.
+func (res *Resource[T]) handleJSONPatch(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	ops, err := decodeBody[[]jsonPatchOp](r.Body)
+	if err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	current, ok := res.getLive(w, r, id)
+	if !ok {
+		return
+	}
+
+	value, err := applyJSONDocument(current, func(doc any) (any, error) {
+		return applyJSONPatch[T](doc, ops)
+	})
+	var opErr *jsonPatchError
+	if errors.As(err, &opErr) {
+		writeValidationErrors(w, r, models.ValidationErrors{{Field: opErr.path, Message: opErr.message}})
+		return
+	}
+	if err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	keepServerFields(value, current)
+
+	if !res.checkVersion(w, r, id, value, current, false) {
+		return
+	}
+
+	res.savePatched(w, r, id, current, value)
+}
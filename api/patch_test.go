@@ -0,0 +1,143 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"rocketseat/models"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+// countingHook counts the events it's told about.
+type countingHook struct {
+	api.NopHook[*models.User]
+	updates atomic.Int32
+}
+
+func (h *countingHook) OnUpdate(context.Context, api.Response[*models.User]) error {
+	h.updates.Add(1)
+	return nil
+}
+
+func TestPatchUpdatesOnlyGivenFields(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+
+	rec := s.Do(http.MethodPatch, "/users/"+user.ID.String(), map[string]any{"biography": "Rewritten"})
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+
+	got := s.GetUser(user.ID)
+	if *got.Biography != "Rewritten" {
+		t.Errorf("got biography %q, want Rewritten", *got.Biography)
+	}
+	if *got.FirstName != "Jane" || *got.LastName != "Doe" || *got.Email != "jane@example.com" {
+		t.Errorf("patch changed fields it didn't set: %+v", got.User)
+	}
+	if got.Version != user.Version+1 {
+		t.Errorf("got version %d, want %d", got.Version, user.Version+1)
+	}
+}
+
+func TestPatchWithoutFieldsWritesNothing(t *testing.T) {
+	for name, contentType := range map[string]string{
+		"json":        "application/json",
+		"merge patch": "application/merge-patch+json",
+	} {
+		t.Run(name, func(t *testing.T) {
+			hook := &countingHook{}
+			cfg := api.DefaultConfig()
+			cfg.EventHook = hook
+			s := apitest.NewTestServerWithConfig(t, cfg)
+			user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+			path := "/users/" + user.ID.String()
+			etag := s.Do(http.MethodGet, path, nil).Header().Get("ETag")
+
+			req := s.NewRequest(http.MethodPatch, path, map[string]any{})
+			req.Header.Set("Content-Type", contentType)
+			rec := s.Serve(req)
+			apitest.ExpectStatus(t, rec, http.StatusOK)
+
+			var answered apitest.User
+			if err := json.Unmarshal(rec.Body.Bytes(), &answered); err != nil {
+				t.Fatal(err)
+			}
+			if answered.ID != user.ID || *answered.FirstName != "Jane" {
+				t.Errorf("got %+v, want the stored user", answered)
+			}
+			if got := rec.Header().Get("ETag"); got != etag {
+				t.Errorf("got ETag %s, want it unchanged from %s", got, etag)
+			}
+
+			got := s.GetUser(user.ID)
+			if got.Version != user.Version || !got.UpdatedAt.Equal(user.UpdatedAt) {
+				t.Errorf("got version %d updated at %v, want %d and %v", got.Version, got.UpdatedAt, user.Version, user.UpdatedAt)
+			}
+			if n := hook.updates.Load(); n != 0 {
+				t.Errorf("got %d update events, want none", n)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	path := "/users/" + user.ID.String()
+
+	mergePatch := func(body string) *httptest.ResponseRecorder {
+		return s.Serve(rawRequest(s, http.MethodPatch, path, "application/merge-patch+json", body))
+	}
+
+	apitest.ExpectStatus(t, mergePatch(`{"biography":"Rewritten"}`), http.StatusOK)
+	got := s.GetUser(user.ID)
+	if *got.Biography != "Rewritten" {
+		t.Errorf("got biography %q, want Rewritten", *got.Biography)
+	}
+	if *got.FirstName != "Jane" || *got.LastName != "Doe" || *got.Email != "jane@example.com" {
+		t.Errorf("omitted fields changed: %+v", got.User)
+	}
+
+	// every user field is required, so clearing one is refused by validation rather than ignored
+	rec := mergePatch(`{"biography":null}`)
+	apitest.ExpectStatus(t, rec, http.StatusUnprocessableEntity)
+	if fields := errorFields(apitest.DecodeValidationErrors(t, rec)); !slices.Equal(fields, []string{"biography"}) {
+		t.Errorf("got errors for %v, want biography", fields)
+	}
+	if got := s.GetUser(user.ID); *got.Biography != "Rewritten" {
+		t.Errorf("got biography %q after a refused patch, want Rewritten", *got.Biography)
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	path := "/users/" + user.ID.String()
+
+	jsonPatch := func(body string) *httptest.ResponseRecorder {
+		return s.Serve(rawRequest(s, http.MethodPatch, path, "application/json-patch+json", body))
+	}
+
+	rec := jsonPatch(`[{"op":"replace","path":"/biography","value":"Rewritten"}]`)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	var answered apitest.User
+	if err := json.Unmarshal(rec.Body.Bytes(), &answered); err != nil {
+		t.Fatal(err)
+	}
+	if *answered.Biography != "Rewritten" || *answered.FirstName != "Jane" {
+		t.Errorf("got %+v, want only the biography replaced", answered.User)
+	}
+
+	rec = jsonPatch(`[{"op":"remove","path":"/nickname"}]`)
+	apitest.ExpectStatus(t, rec, http.StatusUnprocessableEntity)
+	if fields := errorFields(apitest.DecodeValidationErrors(t, rec)); !slices.Equal(fields, []string{"/nickname"}) {
+		t.Errorf("got errors for %v, want /nickname", fields)
+	}
+	if got := s.GetUser(user.ID); *got.Biography != "Rewritten" || got.Version != user.Version+1 {
+		t.Errorf("got %+v after a refused patch, want the replaced user", got.User)
+	}
+}
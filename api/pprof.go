@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http/pprof"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// mountPprof serves net/http/pprof under /debug/pprof. It's routed by hand rather than with
+// chi's middleware.Profiler, which would also claim /debug/vars.
+func mountPprof(r chi.Router) {
+	r.Route("/debug/pprof", func(r chi.Router) {
+		r.HandleFunc("/", pprof.Index)
+		r.HandleFunc("/cmdline", pprof.Cmdline)
+		r.HandleFunc("/profile", pprof.Profile)
+		r.HandleFunc("/symbol", pprof.Symbol)
+		r.HandleFunc("/trace", pprof.Trace)
+		// Index serves the named profiles, like /debug/pprof/heap
+		r.HandleFunc("/*", pprof.Index)
+	})
+}
@@ -0,0 +1,300 @@
+package api
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"rocketseat/models"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Filterable is implemented by models that allow listings to be narrowed by some of their JSON fields.
+type Filterable interface {
+	FilterableFields() []string
+}
+
+type filter struct {
+	field string
+	value string
+}
+
+// parseFilters picks out the query parameters naming filterable fields. Any other parameter is
+// ignored here rather than rejected, since the same query also carries limit, offset and sort.
+func parseFilters[T any](query url.Values) []filter {
+	var zero T
+	filterable, ok := any(zero).(Filterable)
+	if !ok {
+		return nil
+	}
+
+	var filters []filter
+	for _, field := range filterable.FilterableFields() {
+		if query.Has(field) {
+			filters = append(filters, filter{field: field, value: query.Get(field)})
+		}
+	}
+
+	return filters
+}
+
+// listQueryParams are the query parameters every listing understands, besides the filters.
+var listQueryParams = []string{"limit", "offset", "sort", "fields", "include_deleted", "q", "format"}
+
+// unknownQueryParam returns the first query parameter, in sorted order, that a listing of T
+// doesn't understand, or "" if there's none.
+func unknownQueryParam[T any](query url.Values) string {
+	var filterable []string
+	var zero T
+	if f, ok := any(zero).(Filterable); ok {
+		filterable = f.FilterableFields()
+	}
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if !slices.Contains(listQueryParams, key) && !slices.Contains(filterable, key) {
+			return key
+		}
+	}
+
+	return ""
+}
+
+// matchesFilters reports whether every filter matches the model's field exactly, ignoring case.
+func matchesFilters(model any, filters []filter) bool {
+	for _, f := range filters {
+		value, ok := filterValue(model, f.field)
+		if !ok || !strings.EqualFold(value, f.value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterValue is the text a filter on field compares against, false when the model has no
+// such field or it's nil.
+func filterValue(model any, field string) (string, bool) {
+	v, ok := fieldByJSONName(reflect.ValueOf(model), field)
+	if !ok {
+		return "", false
+	}
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+
+	return fmt.Sprint(v.Interface()), true
+}
+
+// FieldIndex indexes records by field the way filters compare it, for models.Store.AddIndex.
+// Listings filtering on an indexed field then only look at the records FindBy returns.
+func FieldIndex[T any](field string) models.IndexFunc[T] {
+	return func(value T) (string, bool) {
+		return filterValue(value, field)
+	}
+}
+
+// Searchable is implemented by models whose text fields can be matched by a free-text search.
+type Searchable interface {
+	SearchableFields() []string
+}
+
+// matchesSearch reports whether term appears, ignoring case, in any of the model's searchable fields.
+func matchesSearch(model any, term string) bool {
+	searchable, ok := model.(Searchable)
+	if !ok {
+		return false
+	}
+
+	term = strings.ToLower(term)
+	for _, field := range searchable.SearchableFields() {
+		v, ok := fieldByJSONName(reflect.ValueOf(model), field)
+		if !ok {
+			continue
+		}
+		for v.Kind() == reflect.Pointer && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.String {
+			continue
+		}
+
+		if strings.Contains(strings.ToLower(v.String()), term) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sortable is implemented by models that allow listings to be ordered by some of their JSON fields.
+type Sortable interface {
+	SortableFields() []string
+}
+
+type sortOrder struct {
+	field      string
+	descending bool
+}
+
+// String gives the order back in the form of the sort parameter.
+func (o sortOrder) String() string {
+	if o.descending {
+		return "-" + o.field
+	}
+	return o.field
+}
+
+// CheckSort returns an error if raw, like "created_at" or "-created_at", isn't a sort order T's
+// listings accept.
+func CheckSort[T any](raw string) error {
+	_, err := parseSort[T](raw)
+	return err
+}
+
+// sortOrder reads the sort query parameter, falling back to the resource's default order.
+func (res *Resource[T]) sortOrder(r *http.Request) (sortOrder, error) {
+	raw := r.URL.Query().Get("sort")
+	if raw == "" {
+		return res.defaultSort, nil
+	}
+
+	return parseSort[T](raw)
+}
+
+// parseSort reads a sort query value like "first_name" or "-first_name", accepting only the
+// fields the model declares as sortable. An empty value keeps the default ordering by ID.
+func parseSort[T any](raw string) (sortOrder, error) {
+	if raw == "" {
+		return sortOrder{}, nil
+	}
+
+	order := sortOrder{field: strings.TrimPrefix(raw, "-"), descending: strings.HasPrefix(raw, "-")}
+
+	var zero T
+	sortable, ok := any(zero).(Sortable)
+	if !ok || !slices.Contains(sortable.SortableFields(), order.field) {
+		return sortOrder{}, fmt.Errorf("cannot sort by %q", order.field)
+	}
+
+	return order, nil
+}
+
+// sortResponses orders items by the requested field, falling back to the ID so equal values
+// keep a stable position across requests.
+func sortResponses[T any](items []Response[T], order sortOrder) {
+	slices.SortFunc(items, func(a, b Response[T]) int {
+		if order.field != "" {
+			av, _ := fieldByJSONName(reflect.ValueOf(a.Model), order.field)
+			bv, _ := fieldByJSONName(reflect.ValueOf(b.Model), order.field)
+
+			c := compareValues(av, bv)
+			if order.descending {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// fieldByJSONName finds the struct field serialized under name, looking inside embedded structs too.
+func fieldByJSONName(v reflect.Value, name string) (reflect.Value, bool) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct && field.Type != timeType {
+			if found, ok := fieldByJSONName(v.Field(i), name); ok {
+				return found, true
+			}
+			continue
+		}
+
+		if tag == name || (tag == "" && field.Name == name) {
+			return v.Field(i), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+// compareValues orders two field values of the same type; unset values sort first.
+func compareValues(a, b reflect.Value) int {
+	for a.IsValid() && a.Kind() == reflect.Pointer {
+		if a.IsNil() {
+			a = reflect.Value{}
+			break
+		}
+		a = a.Elem()
+	}
+	for b.IsValid() && b.Kind() == reflect.Pointer {
+		if b.IsNil() {
+			b = reflect.Value{}
+			break
+		}
+		b = b.Elem()
+	}
+
+	switch {
+	case !a.IsValid() && !b.IsValid():
+		return 0
+	case !a.IsValid():
+		return -1
+	case !b.IsValid():
+		return 1
+	}
+
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time))
+	}
+
+	switch a.Kind() {
+	case reflect.String:
+		return cmp.Compare(a.String(), b.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	case reflect.Bool:
+		return cmp.Compare(boolToInt(a.Bool()), boolToInt(b.Bool()))
+	}
+
+	return 0
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,362 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// insertNamed inserts a user per first name, each with an email of its own.
+func insertNamed(s *apitest.Server, firstNames ...string) []apitest.User {
+	var users []apitest.User
+	for _, name := range firstNames {
+		email := fmt.Sprintf("%s@example.com", strings.ToLower(name))
+		users = append(users, s.InsertUser(apitest.NewUser(name, "Doe", email)))
+	}
+	return users
+}
+
+func firstNames(users []apitest.User) []string {
+	var names []string
+	for _, user := range users {
+		names = append(names, *user.FirstName)
+	}
+	return names
+}
+
+func TestPagination(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	insertNamed(s, "Ann", "Bob", "Cid", "Dan", "Eve")
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"sort=first_name&limit=2", []string{"Ann", "Bob"}},
+		{"sort=first_name&limit=2&offset=2", []string{"Cid", "Dan"}},
+		{"sort=first_name&limit=2&offset=10", nil},
+	}
+	for _, tt := range tests {
+		if got := firstNames(s.ListUsers(tt.query)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSortByEachField(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	for i, name := range [][2]string{{"Cid", "Ann"}, {"Ann", "Bob"}, {"Bob", "Cid"}} {
+		s.InsertUser(apitest.NewUser(name[0], name[1], fmt.Sprintf("user%d@example.com", i)))
+		// created_at must differ between them to sort on it
+		time.Sleep(time.Millisecond)
+	}
+
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{"first_name", []string{"Ann", "Bob", "Cid"}},
+		{"-first_name", []string{"Cid", "Bob", "Ann"}},
+		{"last_name", []string{"Cid", "Ann", "Bob"}},
+		{"-last_name", []string{"Bob", "Ann", "Cid"}},
+		{"created_at", []string{"Cid", "Ann", "Bob"}},
+		{"-created_at", []string{"Bob", "Ann", "Cid"}},
+	}
+	for _, tt := range tests {
+		if got := firstNames(s.ListUsers("sort=" + tt.sort)); !slices.Equal(got, tt.want) {
+			t.Errorf("sort=%s: got %v, want %v", tt.sort, got, tt.want)
+		}
+	}
+}
+
+func TestFilterByFieldValue(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	s.InsertUser(apitest.NewUser("Jane", "Roe", "jane.roe@example.com"))
+	s.InsertUser(apitest.NewUser("John", "Doe", "john@example.com"))
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"first_name=jane", 2},
+		{"first_name=Jane&last_name=Doe", 1},
+		{"first_name=Nobody", 0},
+	}
+	for _, tt := range tests {
+		if got := s.ListUsers(tt.query); len(got) != tt.want {
+			t.Errorf("%s: got %d users, want %d", tt.query, len(got), tt.want)
+		}
+	}
+}
+
+// search fetches GET /users/search with term as q.
+func search(t *testing.T, s *apitest.Server, term string) *httptest.ResponseRecorder {
+	t.Helper()
+	return s.Do(http.MethodGet, "/users/search?q="+url.QueryEscape(term), nil)
+}
+
+func TestSearch(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	for _, user := range []struct{ first, last, bio string }{
+		{"Jason", "Doe", "Keeps bees"},
+		{"Jane", "Hudson", "Plays chess"},
+		{"John", "Roe", "Writes about beekeeping"},
+	} {
+		u := apitest.NewUser(user.first, user.last, strings.ToLower(user.first)+"@example.com")
+		u.Biography = &user.bio
+		s.InsertUser(u)
+	}
+
+	tests := []struct {
+		term string
+		want []string
+	}{
+		{"chess", []string{"Jane"}},
+		{"SON", []string{"Jane", "Jason"}},
+		{"bee", []string{"Jason", "John"}},
+	}
+	for _, tt := range tests {
+		var page struct {
+			Data []apitest.User `json:"data"`
+		}
+		rec := search(t, s, tt.term)
+		apitest.ExpectStatus(t, rec, http.StatusOK)
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		got := firstNames(page.Data)
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("q=%s: got %v, want %v", tt.term, got, tt.want)
+		}
+	}
+
+	if got := apitest.DecodeError(t, search(t, s, " "), http.StatusBadRequest); got.Code != api.ErrCodeInvalidQuery {
+		t.Errorf("got code %q for an empty q, want %q", got.Code, api.ErrCodeInvalidQuery)
+	}
+}
+
+func TestCount(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	insertNamed(s, "Ann", "Bob", "Cid")
+	s.InsertUser(apitest.NewUser("Ann", "Roe", "ann.roe@example.com"))
+
+	for query, want := range map[string]int{"": 4, "first_name=ann": 2} {
+		rec := s.Do(http.MethodGet, "/users/count?"+query, nil)
+		apitest.ExpectStatus(t, rec, http.StatusOK)
+		var got api.CountResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Count != want {
+			t.Errorf("%q: got count %d, want %d", query, got.Count, want)
+		}
+	}
+}
+
+func TestFieldsNarrowsResponses(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+
+	keysOf := func(object map[string]any) []string {
+		return slices.Sorted(maps.Keys(object))
+	}
+	want := []string{"email", "first_name"}
+
+	rec := s.Do(http.MethodGet, "/users/"+user.ID.String()+"?fields=first_name,email", nil)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	var one map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &one); err != nil {
+		t.Fatal(err)
+	}
+	if got := keysOf(one); !slices.Equal(got, want) {
+		t.Errorf("got keys %v fetching one user, want %v", got, want)
+	}
+
+	rec = s.Do(http.MethodGet, "/users?fields=first_name,email", nil)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	var page struct {
+		Data []map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Data) != 1 || !slices.Equal(keysOf(page.Data[0]), want) {
+		t.Errorf("got %v listing, want only %v", page.Data, want)
+	}
+
+	rec = s.Do(http.MethodGet, "/users?fields=password", nil)
+	apitest.DecodeError(t, rec, http.StatusBadRequest)
+}
+
+func TestStrictQueryNamesUnknownParameters(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.StrictQuery = true
+	s := apitest.NewTestServerWithConfig(t, cfg)
+	insertNamed(s, "Ada")
+
+	got := apitest.DecodeError(t, s.Do(http.MethodGet, "/users?limt=10", nil), http.StatusBadRequest)
+	if got.Code != api.ErrCodeInvalidQuery || !strings.Contains(got.Message, `"limt"`) {
+		t.Errorf("got %q %q, want %q naming limt", got.Code, got.Message, api.ErrCodeInvalidQuery)
+	}
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/users?limit=10&sort=first_name", nil), http.StatusOK)
+
+	lax := apitest.NewTestServer(t)
+	apitest.ExpectStatus(t, lax.Do(http.MethodGet, "/users?limt=10", nil), http.StatusOK)
+}
+
+func TestLimitCap(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.MaxLimit = 3
+	s := apitest.NewTestServerWithConfig(t, cfg)
+	insertNamed(s, "Ann", "Bob", "Cid", "Dan", "Eve")
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"limit=2", 2},
+		{"limit=3", 3},
+		{"limit=4", 3},
+	}
+	for _, tt := range tests {
+		if got := len(s.ListUsers(tt.query)); got != tt.want {
+			t.Errorf("%s: got %d users, want %d", tt.query, got, tt.want)
+		}
+	}
+
+	cfg.RejectOverLimit = true
+	strict := apitest.NewTestServerWithConfig(t, cfg)
+	apitest.ExpectStatus(t, strict.Do(http.MethodGet, "/users?limit=3", nil), http.StatusOK)
+	if got := apitest.DecodeError(t, strict.Do(http.MethodGet, "/users?limit=4", nil), http.StatusBadRequest); got.Code != api.ErrCodeInvalidQuery {
+		t.Errorf("got code %q, want %q", got.Code, api.ErrCodeInvalidQuery)
+	}
+}
+
+func TestFormatIDsListsOnlyIDs(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	users := insertNamed(s, "Ann", "Bob")
+	users = append(users, s.InsertUser(apitest.NewUser("Ann", "Roe", "ann.roe@example.com")))
+
+	rec := s.Do(http.MethodGet, "/users?format=ids&first_name=Ann", nil)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	var ids []string
+	if err := json.Unmarshal(rec.Body.Bytes(), &ids); err != nil {
+		t.Fatalf("got %s, want a flat array of strings: %v", rec.Body, err)
+	}
+
+	want := []string{users[0].ID.String(), users[2].ID.String()}
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			t.Errorf("got %q, want a UUID", id)
+		}
+	}
+	if !slices.Equal(slices.Sorted(slices.Values(ids)), slices.Sorted(slices.Values(want))) {
+		t.Errorf("got ids %v, want %v", ids, want)
+	}
+}
+
+func TestListEnvelope(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	insertNamed(s, "Ann", "Bob", "Cid", "Dan", "Eve")
+
+	rec := s.Do(http.MethodGet, "/users?limit=2&offset=1", nil)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	var page struct {
+		Data []json.RawMessage `json:"data"`
+		Meta api.ListMeta      `json:"meta"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Data) != 2 {
+		t.Errorf("got %d users in data, want 2", len(page.Data))
+	}
+	if want := (api.ListMeta{Total: 5, Limit: 2, Offset: 1}); page.Meta != want {
+		t.Errorf("got meta %+v, want %+v", page.Meta, want)
+	}
+
+	cfg := api.DefaultConfig()
+	cfg.BareLists = true
+	bare := apitest.NewTestServerWithConfig(t, cfg)
+	insertNamed(bare, "Ann", "Bob", "Cid")
+	rec = bare.Do(http.MethodGet, "/users?limit=2", nil)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	var users []apitest.User
+	if err := json.Unmarshal(rec.Body.Bytes(), &users); err != nil {
+		t.Fatalf("got %s, want a bare array: %v", rec.Body, err)
+	}
+	if len(users) != 2 || rec.Header().Get("X-Total-Count") != "3" {
+		t.Errorf("got %d users and X-Total-Count %q, want 2 and 3", len(users), rec.Header().Get("X-Total-Count"))
+	}
+}
+
+func TestHeadCountsUsers(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	insertNamed(s, "Ann", "Bob", "Cid")
+	s.InsertUser(apitest.NewUser("Ann", "Roe", "ann.roe@example.com"))
+
+	tests := []struct {
+		query, want string
+	}{
+		{"", "4"},
+		{"?first_name=Ann", "2"},
+	}
+	for _, tt := range tests {
+		rec := s.Do(http.MethodHead, "/users"+tt.query, nil)
+		apitest.ExpectStatus(t, rec, http.StatusOK)
+		if got := rec.Header().Get("X-Total-Count"); got != tt.want {
+			t.Errorf("%q: got X-Total-Count %q, want %s", tt.query, got, tt.want)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("%q: got body %s, want none", tt.query, rec.Body)
+		}
+	}
+}
+
+func TestUnsortedListsAreStable(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	var names []string
+	for i := range 20 {
+		names = append(names, fmt.Sprintf("User%02d", i))
+	}
+	insertNamed(s, names...)
+
+	first, second := firstNames(s.ListUsers("")), firstNames(s.ListUsers(""))
+	if !slices.Equal(first, second) {
+		t.Errorf("got %v then %v, want the same order", first, second)
+	}
+	// the default sort is by created_at, so that's insertion order
+	if !slices.Equal(first, names) {
+		t.Errorf("got %v, want insertion order %v", first, names)
+	}
+}
+
+func TestOffsetBounds(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	insertNamed(s, "Ann", "Bob")
+
+	rec := s.Do(http.MethodGet, "/users?offset=99999999999999999999", nil)
+	if got := apitest.DecodeError(t, rec, http.StatusBadRequest); got.Code != api.ErrCodeInvalidQuery {
+		t.Errorf("got code %q, want %q", got.Code, api.ErrCodeInvalidQuery)
+	}
+
+	nearMax := strconv.Itoa(math.MaxInt - 1)
+	if users := s.ListUsers("offset=" + nearMax + "&limit=2"); len(users) != 0 {
+		t.Errorf("got %d users past the end, want none", len(users))
+	}
+}
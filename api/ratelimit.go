@@ -0,0 +1,119 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RateLimitConfig struct {
+	// RequestsPerSecond is how fast each client's bucket refills. Zero disables rate limiting.
+	RequestsPerSecond float64
+	// Burst is how many requests a client can make at once before being throttled.
+	Burst int
+	// TrustForwardedFor keys clients by the first X-Forwarded-For address, which is only safe
+	// behind a proxy that sets the header itself.
+	TrustForwardedFor bool
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter keeps one token bucket per client. Buckets that have refilled completely are
+// dropped from time to time, since they're no different from a fresh one.
+type rateLimiter struct {
+	mu        sync.Mutex
+	cfg       RateLimitConfig
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(cfg RateLimitConfig) *rateLimiter {
+	return &rateLimiter{cfg: cfg, buckets: map[string]*bucket{}, lastSweep: time.Now()}
+}
+
+// allow takes a token from key's bucket, or reports how long until one is available.
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	burst := float64(max(l.cfg.Burst, 1))
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*l.cfg.RequestsPerSecond)
+	b.last = now
+
+	l.sweep(now, burst)
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.cfg.RequestsPerSecond * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+func (l *rateLimiter) sweep(now time.Time, burst float64) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.cfg.RequestsPerSecond >= burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *rateLimiter) clientKey(r *http.Request) string {
+	if l.cfg.TrustForwardedFor {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// rateLimit answers 429 with a Retry-After header once a client runs out of tokens.
+func rateLimit(cfg RateLimitConfig) func(http.Handler) http.Handler {
+	if cfg.RequestsPerSecond <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	limiter := newRateLimiter(cfg)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, wait := limiter.allow(limiter.clientKey(r), time.Now())
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				writeError(w, http.StatusTooManyRequests, ErrCodeRateLimited, "Too many requests, slow down")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
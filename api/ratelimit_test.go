@@ -0,0 +1,32 @@
+package api_test
+
+import (
+	"net/http"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"strconv"
+	"testing"
+)
+
+func TestRateLimitAnswers429(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.RateLimit = api.RateLimitConfig{RequestsPerSecond: 0.5, Burst: 2}
+	s := apitest.NewTestServerWithConfig(t, cfg)
+
+	for range cfg.RateLimit.Burst {
+		apitest.ExpectStatus(t, s.Do(http.MethodGet, "/users", nil), http.StatusOK)
+	}
+
+	rec := s.Do(http.MethodGet, "/users", nil)
+	if got := apitest.DecodeError(t, rec, http.StatusTooManyRequests); got.Code != api.ErrCodeRateLimited {
+		t.Errorf("got code %q, want %q", got.Code, api.ErrCodeRateLimited)
+	}
+	if seconds, err := strconv.Atoi(rec.Header().Get("Retry-After")); err != nil || seconds < 1 {
+		t.Errorf("got Retry-After %q, want a whole number of seconds", rec.Header().Get("Retry-After"))
+	}
+
+	// other clients have buckets of their own
+	req := s.NewRequest(http.MethodGet, "/users", nil)
+	req.RemoteAddr = "198.51.100.7:4321"
+	apitest.ExpectStatus(t, s.Serve(req), http.StatusOK)
+}
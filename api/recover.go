@@ -0,0 +1,49 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// handlerPanic carries a panic from a handler's goroutine to the one serving the request,
+// along with the stack it was raised on, which the re-panic would otherwise lose.
+type handlerPanic struct {
+	value any
+	stack []byte
+}
+
+// recoverer turns a panicking handler into a JSON 500, logging the panic with the request's ID.
+// The stack trace only goes to the log when logStack is set, and never to the client.
+func recoverer(logger *slog.Logger, logStack bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+
+				stack := debug.Stack()
+				if hp, ok := p.(handlerPanic); ok {
+					p, stack = hp.value, hp.stack
+				}
+				// net/http uses this panic to abort a response on purpose
+				if err, ok := p.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(err)
+				}
+
+				attrs := []any{"panic", p, "method", r.Method, "path", r.URL.Path}
+				if logStack {
+					attrs = append(attrs, "stack", string(stack))
+				}
+				logger.ErrorContext(r.Context(), "handler panicked", attrs...)
+
+				writeError(w, http.StatusInternalServerError, ErrCodeInternal, "Something went wrong while handling the request")
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
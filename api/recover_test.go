@@ -0,0 +1,47 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"rocketseat/models"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// panickingStore panics on every Get, like a bug deep in a handler would.
+type panickingStore struct {
+	*models.Store[*models.User]
+}
+
+func (panickingStore) Get(context.Context, uuid.UUID) (*models.User, error) {
+	panic("boom")
+}
+
+func TestPanicAnswersJSON500(t *testing.T) {
+	var logs bytes.Buffer
+	cfg := api.DefaultConfig()
+	cfg.Logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	handler := api.NewHandler(panickingStore{models.NewStore[*models.User]()}, cfg)
+
+	for range 2 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/"+uuid.NewString(), nil))
+
+		got := apitest.DecodeError(t, rec, http.StatusInternalServerError)
+		if got.Code != api.ErrCodeInternal || strings.Contains(got.Message, "boom") {
+			t.Errorf("got %+v, want an internal_error that doesn't leak the panic", got)
+		}
+	}
+
+	entry := logEntry(t, &logs, "handler panicked")
+	if entry["panic"] != "boom" || entry["stack"] == nil {
+		t.Errorf("got %v, want the panic logged with its stack", entry)
+	}
+}
@@ -0,0 +1,28 @@
+package api_test
+
+import (
+	"net/http"
+	"rocketseat/api/apitest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRequestIDIsEchoed(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	req := s.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("X-Request-ID", "trace-abc-123")
+	if got := s.Serve(req).Header().Get("X-Request-ID"); got != "trace-abc-123" {
+		t.Errorf("got X-Request-ID %q, want the one sent", got)
+	}
+
+	// one the client didn't send, or that could break up log lines, is replaced with a fresh one
+	for _, sent := range []string{"", "bad id\nwith newline"} {
+		req := s.NewRequest(http.MethodGet, "/users", nil)
+		req.Header.Set("X-Request-ID", sent)
+		if got := s.Serve(req).Header().Get("X-Request-ID"); uuid.Validate(got) != nil {
+			t.Errorf("sent %q, got X-Request-ID %q, want a generated UUID", sent, got)
+		}
+	}
+}
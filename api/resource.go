@@ -0,0 +1,1685 @@
+package api
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"rocketseat/models"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// Validator is implemented by models that know how to check their own fields.
+// Models that don't implement it fall back to requiring every JSON field to be present.
+type Validator interface {
+	Validate() models.ValidationErrors
+}
+
+// LengthValidator is implemented by models whose length limits can be set when validating.
+type LengthValidator interface {
+	ValidateWith(maxLengths models.MaxLengths) models.ValidationErrors
+}
+
+// Timestamped is implemented by models embedding models.Timestamps.
+type Timestamped interface {
+	GetCreatedAt() time.Time
+	SetCreatedAt(time.Time)
+	GetUpdatedAt() time.Time
+	SetUpdatedAt(time.Time)
+}
+
+// SoftDeletable is implemented by models embedding models.SoftDelete.
+type SoftDeletable interface {
+	GetDeletedAt() *time.Time
+	SetDeletedAt(*time.Time)
+}
+
+func isDeleted(value any) bool {
+	deletable, ok := value.(SoftDeletable)
+	return ok && deletable.GetDeletedAt() != nil
+}
+
+// clearDeleted drops any deleted_at a client sent, since writes only ever target live records.
+func clearDeleted(value any) {
+	if deletable, ok := value.(SoftDeletable); ok {
+		deletable.SetDeletedAt(nil)
+	}
+}
+
+// Versioned is implemented by models embedding models.Versioning.
+type Versioned interface {
+	GetVersion() int
+	SetVersion(int)
+}
+
+// checkVersion makes sure a write to current, stored under id, is based on what's stored now.
+// An If-Match header is checked by checkIfMatch; otherwise a version field in the body must be
+// the stored one. PUT must name a version one way or the other, while PATCH only checks one
+// when the client sends it.
+func (res *Resource[T]) checkVersion(w http.ResponseWriter, r *http.Request, id uuid.UUID, body any, current T, required bool) bool {
+	if header := r.Header.Get("If-Match"); header != "" {
+		return res.checkIfMatch(w, r, id, header, current)
+	}
+
+	stored, ok := any(current).(Versioned)
+	if !ok {
+		return true
+	}
+
+	versioned, isVersioned := body.(Versioned)
+	if !isVersioned || isNilPointer(body) || versioned.GetVersion() < 1 {
+		if required {
+			writeError(w, http.StatusPreconditionRequired, ErrCodePreconditionRequired, "Send the version being updated in an If-Match header or the version field")
+			return false
+		}
+		return true
+	}
+
+	if expected := versioned.GetVersion(); expected != stored.GetVersion() {
+		writeError(w, http.StatusConflict, ErrCodeVersionMismatch, fmt.Sprintf("Version %d is stale, the current version is %d", expected, stored.GetVersion()))
+		return false
+	}
+
+	return true
+}
+
+// checkIfMatch answers 412 unless header, an If-Match header, lists current, stored under id.
+// A record is listed by the ETag GET answers it with, by its version like "3" for versioned
+// models, or by "*".
+func (res *Resource[T]) checkIfMatch(w http.ResponseWriter, r *http.Request, id uuid.UUID, header string, current T) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	data, _, err := encodeResponse(r, res.response(id, current))
+	if err != nil {
+		loggerFrom(r.Context()).ErrorContext(r.Context(), "failed to marshal response", "error", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "Error while parsing the response")
+		return false
+	}
+	etag := entityTag(data)
+
+	versioned, isVersioned := any(current).(Versioned)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == etag {
+			return true
+		}
+		if !isVersioned {
+			continue
+		}
+		if version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(candidate, "W/"), `"`)); err == nil && version == versioned.GetVersion() {
+			return true
+		}
+	}
+
+	writeError(w, http.StatusPreconditionFailed, ErrCodePreconditionFailed, fmt.Sprintf("If-Match doesn't match the current %s", strings.ToLower(res.name)))
+	return false
+}
+
+// checkUnmodifiedSince answers 412 when current was updated after the time in an
+// If-Unmodified-Since header. HTTP dates only have second precision, so an update within the
+// named second still passes. given reports whether a usable header was sent; unparsable dates
+// are ignored, as RFC 9110 asks.
+func checkUnmodifiedSince(w http.ResponseWriter, r *http.Request, current any) (given, ok bool) {
+	stamped, isStamped := current.(Timestamped)
+	header := r.Header.Get("If-Unmodified-Since")
+	if !isStamped || header == "" {
+		return false, true
+	}
+
+	since, err := http.ParseTime(header)
+	if err != nil {
+		return false, true
+	}
+
+	if stamped.GetUpdatedAt().Truncate(time.Second).After(since) {
+		writeError(w, http.StatusPreconditionFailed, ErrCodePreconditionFailed, "The record was modified after "+since.UTC().Format(http.TimeFormat))
+		return true, false
+	}
+
+	return true, true
+}
+
+// bumpVersion sets value's version to the one after current's.
+func bumpVersion(value, current any) {
+	if versioned, ok := value.(Versioned); ok {
+		versioned.SetVersion(current.(Versioned).GetVersion() + 1)
+	}
+}
+
+// prepareInsert readies a freshly decoded value for storage as a new, live record.
+func prepareInsert(value any, now time.Time) {
+	clearDeleted(value)
+	if versioned, ok := value.(Versioned); ok {
+		versioned.SetVersion(1)
+	}
+	if stamped, ok := value.(Timestamped); ok {
+		stamped.SetCreatedAt(now)
+		stamped.SetUpdatedAt(now)
+	}
+}
+
+// Resource exposes CRUD handlers for any model kept in a models.Storage.
+type Resource[T any] struct {
+	db   models.Storage[T]
+	name string
+	// prefix is the path the routes were registered under, set by RegisterRoutes.
+	prefix string
+	// allowClear registers DELETE on the collection, which wipes every record.
+	allowClear bool
+	// strictQuery makes listings reject query parameters they don't understand.
+	strictQuery bool
+	// maxLimit caps the page size of listings, zero meaning no cap. Larger limits are clamped,
+	// or rejected with a 400 when rejectOverLimit is set.
+	maxLimit        int
+	rejectOverLimit bool
+	// putCreates lets PUT create a record at an ID nothing is stored under, instead of a 404.
+	putCreates bool
+	// idempotentDelete answers a DELETE of a missing or already deleted record with 204, not 404.
+	idempotentDelete bool
+	// bareLists answers listings with a bare array instead of the envelope with metadata.
+	bareLists bool
+	// maxLengths overrides the length limits of the model's validate tags.
+	maxLengths models.MaxLengths
+	// defaultSort orders listings that don't ask for an order, ties and the zero value going by ID.
+	defaultSort sortOrder
+	// relations are what ?expand= can nest in a record, by name.
+	relations map[string]Expander[T]
+	// uniqueFields are the fields WithUnique made unique, besides the model's own Unique key.
+	uniqueFields []string
+	// uniqueCaseSensitive makes "Jane" and "jane" different values of uniqueFields.
+	uniqueCaseSensitive bool
+	ids                 IDGenerator
+	// idFormat is how IDs are written in responses and Location headers, one of the IDFormat
+	// constants.
+	idFormat string
+	// events streams changes to GET /events. It's always the first of hooks.
+	events *EventStream[T]
+	hooks  []EventHook[T]
+	logger *slog.Logger
+	// middlewares wrap each route's handler, running once the route has been matched.
+	middlewares []func(http.Handler) http.Handler
+}
+
+func NewResource[T any](db models.Storage[T]) *Resource[T] {
+	events := NewEventStream[T]()
+	res := &Resource[T]{db: db, name: modelName[T](), maxLimit: defaultMaxLimit, logger: slog.Default(), ids: UUIDv4{}, events: events, hooks: []EventHook[T]{events}}
+	// the keys are worked out on every write, so fields made unique later are enforced too
+	if enforcer, ok := db.(models.UniqueEnforcer[T]); ok {
+		enforcer.SetUniqueKeys(res.liveUniqueKeys)
+	}
+
+	return res
+}
+
+// WithLogger makes the resource's handlers log to logger instead of slog.Default().
+func (res *Resource[T]) WithLogger(logger *slog.Logger) *Resource[T] {
+	res.logger = logger
+	return res
+}
+
+// WithMiddleware wraps every route of the resource in middlewares. Unlike middleware used on
+// the router RegisterRoutes is given, they only run once routing is done, so they may hand the
+// request to another goroutine without it racing the router.
+func (res *Resource[T]) WithMiddleware(middlewares ...func(http.Handler) http.Handler) *Resource[T] {
+	res.middlewares = append(res.middlewares, middlewares...)
+	return res
+}
+
+// WithDefaultSort orders listings without a sort parameter by raw, which takes the same form as
+// the parameter. It panics on fields T can't be sorted by, since that's a mistake in the code
+// setting up the routes.
+func (res *Resource[T]) WithDefaultSort(raw string) *Resource[T] {
+	order, err := parseSort[T](raw)
+	if err != nil {
+		panic("api: WithDefaultSort: " + err.Error())
+	}
+
+	res.defaultSort = order
+	return res
+}
+
+func (res *Resource[T]) RegisterRoutes(r chi.Router, prefix string) {
+	res.prefix = prefix
+	r.With(res.middlewares...).Get(prefix+".csv", res.handleExportCSV())
+	r.Route(prefix, func(r chi.Router) {
+		r = r.With(res.middlewares...)
+		r.Get("/", res.handleFindAll())
+		r.Head("/", res.handleHeadList())
+		r.Get("/search", res.handleSearch())
+		r.Get("/count", res.handleCount())
+		r.Get("/events", res.handleEvents())
+		r.Get("/schema", res.handleSchema())
+		r.Get("/{id}", res.handleFindById())
+		r.Head("/{id}", res.handleExists())
+		r.Post("/", res.handleInsert())
+		r.Post("/batch", res.handleBatchInsert())
+		r.Post("/batch-delete", res.handleBatchDelete())
+		r.Put("/{id}", res.handleUpdate())
+		r.Patch("/{id}", res.handlePatch())
+		r.Delete("/{id}", res.handleDelete())
+		var zero T
+		if _, ok := any(zero).(SoftDeletable); ok {
+			r.Post("/{id}/restore", res.handleRestore())
+		}
+		if res.allowClear {
+			r.Delete("/", res.handleClear())
+		}
+	})
+}
+
+// modelName returns the bare type name of T, e.g. "User" for *models.User, for use in messages.
+func modelName[T any]() string {
+	t := reflect.TypeFor[T]()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t.Name()
+}
+
+// Response flattens the model's own fields next to its ID when marshaled. Soft-deleted
+// records also get "deleted": true, so they stand out in listings mixing them with live ones.
+type Response[T any] struct {
+	ID    uuid.UUID
+	Model T
+	// fields, when set, narrows the JSON down to just these keys, in this order.
+	fields []string
+	// expanded are the related objects ?expand= asked for, added after the fields.
+	expanded []relation
+	// idFormat is how the ID is written, one of the IDFormat constants. Empty means canonical.
+	idFormat string
+}
+
+// errNilModel is returned for a Response whose model is a nil pointer, which would otherwise
+// marshal to just its ID and look like a record with every field empty.
+var errNilModel = errors.New("response has no model")
+
+// NewResponse pairs a stored value with its ID, refusing a nil model.
+func NewResponse[T any](id uuid.UUID, model T) (Response[T], error) {
+	if isNilPointer(model) {
+		return Response[T]{}, fmt.Errorf("%s %s: %w", reflect.TypeFor[T](), id, errNilModel)
+	}
+
+	return Response[T]{ID: id, Model: model}, nil
+}
+
+func (resp Response[T]) MarshalJSON() ([]byte, error) {
+	if isNilPointer(resp.Model) {
+		return nil, fmt.Errorf("%s %s: %w", reflect.TypeFor[T](), resp.ID, errNilModel)
+	}
+
+	idJson, err := json.Marshal(formatID(resp.idFormat, resp.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	modelJson, err := json.Marshal(resp.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	modelJson = bytes.TrimSpace(modelJson)
+	if len(modelJson) < 2 || modelJson[0] != '{' {
+		return nil, fmt.Errorf("%s does not marshal to a JSON object", reflect.TypeFor[T]())
+	}
+
+	// splice the id in as the first key, keeping the model's own field order after it
+	var buf bytes.Buffer
+	buf.WriteString(`{"id":`)
+	buf.Write(idJson)
+	if isDeleted(resp.Model) {
+		buf.WriteString(`,"deleted":true`)
+	}
+	if rest := bytes.TrimSpace(modelJson[1:]); len(rest) > 0 && rest[0] != '}' {
+		buf.WriteByte(',')
+	}
+	buf.Write(modelJson[1:])
+
+	object := buf.Bytes()
+	if len(resp.fields) > 0 {
+		if object, err = projectJSON(object, resp.fields); err != nil {
+			return nil, err
+		}
+	}
+	if len(resp.expanded) == 0 {
+		return object, nil
+	}
+
+	return appendRelations(object, resp.expanded)
+}
+
+// projectJSON rewrites a JSON object to hold only fields, in that order.
+func projectJSON(object []byte, fields []string) ([]byte, error) {
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(object, &all); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range fields {
+		key, err := json.Marshal(field)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		if value, ok := all[field]; ok {
+			buf.Write(value)
+		} else {
+			buf.WriteString("null")
+		}
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// parseFields reads the fields query parameter, the comma-separated JSON fields a client wants
+// back. It returns nil when the parameter is absent, meaning every field.
+func parseFields[T any](r *http.Request) ([]string, error) {
+	raw := r.URL.Query().Get("fields")
+	if raw == "" {
+		return nil, nil
+	}
+
+	known := append([]string{"id"}, jsonFieldNames(reflect.TypeFor[T]())...)
+
+	var fields []string
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || slices.Contains(fields, field) {
+			continue
+		}
+		if !slices.Contains(known, field) {
+			return nil, fmt.Errorf("unknown field %q, expected some of %s", field, strings.Join(known, ", "))
+		}
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+type ListResponse[T any] struct {
+	Data []Response[T] `json:"data"`
+	Meta ListMeta      `json:"meta"`
+}
+
+type ListMeta struct {
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+func decodeBody[T any](body io.Reader) (T, error) {
+	var value T
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return value, err
+	}
+	if err := checkDuplicateKeys(content); err != nil {
+		return value, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&value); err != nil {
+		return value, err
+	}
+
+	return value, nil
+}
+
+// duplicateKeyError is a JSON object that repeats a key. encoding/json would silently keep the
+// last value, letting a body pass validation with a field the client may not have meant.
+type duplicateKeyError struct {
+	key string
+}
+
+func (e *duplicateKeyError) Error() string {
+	return "duplicate field: " + e.key
+}
+
+// checkDuplicateKeys returns a *duplicateKeyError for the first object in content, at any
+// depth, that repeats a key. Malformed JSON is left for the decode that follows to report.
+func checkDuplicateKeys(content []byte) error {
+	var duplicate *duplicateKeyError
+	if err := scanDuplicateKeys(json.NewDecoder(bytes.NewReader(content))); errors.As(err, &duplicate) {
+		return err
+	}
+
+	return nil
+}
+
+func scanDuplicateKeys(decoder *json.Decoder) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		seen := map[string]bool{}
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+
+			key, _ := token.(string)
+			if seen[key] {
+				return &duplicateKeyError{key: key}
+			}
+			seen[key] = true
+
+			if err := scanDuplicateKeys(decoder); err != nil {
+				return err
+			}
+		}
+	case json.Delim('['):
+		for decoder.More() {
+			if err := scanDuplicateKeys(decoder); err != nil {
+				return err
+			}
+		}
+	default:
+		return nil
+	}
+
+	// the closing delimiter
+	_, err = decoder.Token()
+	return err
+}
+
+// decodeBodyWithID decodes body like decodeBody, except that an "id" key is taken out first and
+// returned on its own, so clients can choose the ID of the record they create.
+func decodeBodyWithID[T any](body io.Reader) (T, string, error) {
+	var value T
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return value, "", err
+	}
+	// the map below would drop duplicates before decodeBody could see them
+	if err := checkDuplicateKeys(content); err != nil {
+		return value, "", err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(content, &fields); err == nil {
+		if rawID, ok := fields["id"]; ok {
+			var id string
+			if err := json.Unmarshal(rawID, &id); err != nil {
+				return value, "", fmt.Errorf("id must be a string: %w", err)
+			}
+			delete(fields, "id")
+
+			if content, err = json.Marshal(fields); err != nil {
+				return value, "", err
+			}
+
+			value, err := decodeBody[T](bytes.NewReader(content))
+			return value, id, err
+		}
+	}
+
+	value, err = decodeBody[T](bytes.NewReader(content))
+	return value, "", err
+}
+
+// clientID picks the ID a client asked for, from the body or the Idempotency-Key header.
+// It returns uuid.Nil when neither is set.
+func clientID(r *http.Request, bodyID string) (uuid.UUID, error) {
+	headerID := r.Header.Get("Idempotency-Key")
+	if bodyID != "" && headerID != "" && bodyID != headerID {
+		return uuid.Nil, errors.New("id in the body and Idempotency-Key header don't match")
+	}
+
+	raw := cmp.Or(bodyID, headerID)
+	if raw == "" {
+		return uuid.Nil, nil
+	}
+
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, errors.New("id must be a valid UUID")
+	}
+
+	return id, nil
+}
+
+// bodyIDMatches rejects a write whose body names a different record than its URL. Bodies
+// without an id are fine, since the URL already says which record is meant.
+func bodyIDMatches(w http.ResponseWriter, bodyID string, urlID uuid.UUID) bool {
+	if bodyID == "" {
+		return true
+	}
+
+	if id, err := uuid.Parse(bodyID); err != nil || id != urlID {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidID, "id in the body doesn't match the URL")
+		return false
+	}
+
+	return true
+}
+
+func isNilPointer(value any) bool {
+	rv := reflect.ValueOf(value)
+	return !rv.IsValid() || (rv.Kind() == reflect.Pointer && rv.IsNil())
+}
+
+// validate is the package-level validate with the resource's max lengths applied, for models
+// that support them.
+func (res *Resource[T]) validate(value T) models.ValidationErrors {
+	if v, ok := any(value).(LengthValidator); ok && len(res.maxLengths) > 0 && !isNilPointer(value) {
+		return v.ValidateWith(res.maxLengths)
+	}
+
+	return validate(value)
+}
+
+// validate runs the model's own Validate method, or checks every JSON field is set when there isn't one.
+func validate(value any) models.ValidationErrors {
+	if isNilPointer(value) {
+		return models.ValidationErrors{{Field: "", Message: "must be a JSON object"}}
+	}
+
+	if v, ok := value.(Validator); ok {
+		return v.Validate()
+	}
+
+	var errs models.ValidationErrors
+
+	valueJson, err := json.Marshal(value)
+	if err != nil {
+		errs.Add("", err.Error())
+		return errs
+	}
+	var valueMap map[string]any
+	if err := json.Unmarshal(valueJson, &valueMap); err != nil {
+		errs.Add("", err.Error())
+		return errs
+	}
+
+	keys := make([]string, 0, len(valueMap))
+	for key := range valueMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if valueMap[key] == nil {
+			errs.Add(key, "required")
+		}
+	}
+
+	return errs
+}
+
+// clone returns a shallow copy of value, following one level of pointer so the copy can be
+// changed without touching the value other readers of the store still hold.
+func clone[T any](value T) T {
+	v := reflect.ValueOf(&value).Elem()
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return value
+	}
+
+	copied := reflect.New(v.Type().Elem())
+	copied.Elem().Set(v.Elem())
+	v.Set(copied)
+
+	return value
+}
+
+// mergeNonNil returns a copy of current with every nil-able field that is set in patch copied over.
+// Working on a copy means readers holding the stored value never see a half-applied patch.
+func mergeNonNil[T any](current, patch T) T {
+	current = clone(current)
+
+	dst := reflect.ValueOf(&current).Elem()
+	src := reflect.ValueOf(patch)
+	if dst.Kind() == reflect.Pointer {
+		if dst.IsNil() || src.IsNil() {
+			return current
+		}
+		dst = dst.Elem()
+		src = src.Elem()
+	}
+	if dst.Kind() != reflect.Struct {
+		return current
+	}
+
+	for i := range dst.NumField() {
+		field := src.Field(i)
+		switch field.Kind() {
+		case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+			if !field.IsNil() && dst.Field(i).CanSet() {
+				dst.Field(i).Set(field)
+			}
+		}
+	}
+
+	return current
+}
+
+// notFound answers 404 naming the resource and the ID from the URL, so clients can log which
+// lookup failed.
+func (res *Resource[T]) notFound(w http.ResponseWriter, r *http.Request) {
+	writeErrorDetail(w, http.StatusNotFound, ErrorDetail{
+		Code:     ErrCodeNotFound,
+		Message:  res.name + " not found",
+		Resource: strings.ToLower(res.name),
+		ID:       chi.URLParam(r, "id"),
+	})
+}
+
+// writeStoreError turns an error from the storage backend into the matching response.
+func (res *Resource[T]) writeStoreError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		res.notFound(w, r)
+	case errors.Is(err, models.ErrConflict):
+		message := fmt.Sprintf("a %s like this already exists", strings.ToLower(res.name))
+		var conflict *models.ConflictError
+		if errors.As(err, &conflict) {
+			message = fmt.Sprintf("a %s with this %s already exists", strings.ToLower(res.name), conflict.Field)
+		}
+		writeError(w, http.StatusConflict, ErrCodeConflict, message)
+	case errors.Is(err, models.ErrFull):
+		message := fmt.Sprintf("No room for another %s", strings.ToLower(res.name))
+		var full *models.FullError
+		if errors.As(err, &full) {
+			message = fmt.Sprintf("No room for another %s, at most %d can be stored", strings.ToLower(res.name), full.Max)
+		}
+		writeError(w, http.StatusInsufficientStorage, ErrCodeStorageFull, message)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// the client has gone or the timeout middleware has answered already, so this rarely lands
+		res.logger.WarnContext(r.Context(), "storage call abandoned", "error", err)
+		writeError(w, http.StatusServiceUnavailable, ErrCodeTimeout, "Request ended before storage answered")
+	default:
+		res.logger.ErrorContext(r.Context(), "storage error", "error", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "Error while accessing storage")
+	}
+}
+
+func (res *Resource[T]) handleFindAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res.writeList(w, r, nil)
+	}
+}
+
+func (res *Resource[T]) handleSearch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		term := strings.TrimSpace(r.URL.Query().Get("q"))
+		if term == "" {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidQuery, "q must not be empty")
+			return
+		}
+
+		res.writeList(w, r, func(value T) bool {
+			return matchesSearch(value, term)
+		})
+	}
+}
+
+// writeList answers a listing request with the paginated envelope, applying the sort and
+// pagination query parameters on top of what collect selects. With ?format=ids it answers a
+// flat array of every selected ID instead.
+func (res *Resource[T]) writeList(w http.ResponseWriter, r *http.Request, match func(T) bool) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "ids" {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidQuery, `format must be "ids"`)
+		return
+	}
+
+	limit, err := parseNonNegativeQuery(r, "limit", defaultLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidQuery, err.Error())
+		return
+	}
+	if res.maxLimit > 0 && limit > res.maxLimit {
+		if res.rejectOverLimit {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidQuery, fmt.Sprintf("limit must not exceed %d", res.maxLimit))
+			return
+		}
+		limit = res.maxLimit
+	}
+
+	offset, err := parseNonNegativeQuery(r, "offset", defaultOffset)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidQuery, err.Error())
+		return
+	}
+
+	order, err := res.sortOrder(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidQuery, err.Error())
+		return
+	}
+
+	fields, err := parseFields[T](r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidQuery, err.Error())
+		return
+	}
+
+	items, ok := res.collect(w, r, match)
+	if !ok {
+		return
+	}
+
+	// map iteration order is random, so always sort to keep pages stable between requests
+	sortResponses(items, order)
+
+	// sync clients want the whole set to diff against their cache, so IDs aren't paginated
+	if format == "ids" {
+		ids := make([]string, len(items))
+		for i, item := range items {
+			ids[i] = formatID(res.idFormat, item.ID)
+		}
+		writeJSON(w, r, http.StatusOK, ids)
+		return
+	}
+
+	// limit can be as large as an int goes, so start+limit could wrap around
+	start := min(offset, len(items))
+	end := start + min(limit, len(items)-start)
+	for i := start; i < end; i++ {
+		items[i].fields = fields
+	}
+
+	// older clients expect a bare array, so the total moves to a header for them
+	if res.bareLists {
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(items)))
+		writeJSON(w, r, http.StatusOK, items[start:end])
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, ListResponse[T]{
+		Data: items[start:end],
+		Meta: ListMeta{Total: len(items), Limit: limit, Offset: offset},
+	})
+}
+
+// collect returns the records selected by the include_deleted and field filter query
+// parameters, narrowed further by match when it's set. It writes the error response itself
+// and reports false when the query is invalid or the store fails, which in strict mode
+// includes naming a parameter no listing understands.
+func (res *Resource[T]) collect(w http.ResponseWriter, r *http.Request, match func(T) bool) ([]Response[T], bool) {
+	if res.strictQuery {
+		if key := unknownQueryParam[T](r.URL.Query()); key != "" {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidQuery, fmt.Sprintf("unknown query parameter %q", key))
+			return nil, false
+		}
+	}
+
+	includeDeleted := false
+	if raw := r.URL.Query().Get("include_deleted"); raw != "" {
+		var err error
+		includeDeleted, err = strconv.ParseBool(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidQuery, "include_deleted must be a boolean")
+			return nil, false
+		}
+	}
+
+	filters := parseFilters[T](r.URL.Query())
+
+	all, err := res.candidates(r.Context(), filters)
+	if err != nil {
+		res.writeStoreError(w, r, err)
+		return nil, false
+	}
+
+	items := []Response[T]{}
+	for _, entry := range all {
+		key, value := entry.ID, entry.Value
+		if !includeDeleted && isDeleted(value) {
+			continue
+		}
+		if !matchesFilters(value, filters) {
+			continue
+		}
+		if match != nil && !match(value) {
+			continue
+		}
+		items = append(items, res.response(key, value))
+	}
+
+	return items, true
+}
+
+// list returns every record sorted by ID, as copies when the backend can make them.
+func (res *Resource[T]) list(ctx context.Context) ([]models.Entry[T], error) {
+	if lister, ok := res.db.(models.Lister[T]); ok {
+		return lister.List(ctx)
+	}
+
+	all, err := res.db.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.Entry[T], 0, len(all))
+	for id, value := range all {
+		entries = append(entries, models.Entry[T]{ID: id, Value: value})
+	}
+	slices.SortFunc(entries, func(a, b models.Entry[T]) int {
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+
+	return entries, nil
+}
+
+// candidates returns the records a listing with filters has to look at: what FindBy has for the
+// first filter on an indexed field, or every record when there's none.
+func (res *Resource[T]) candidates(ctx context.Context, filters []filter) ([]models.Entry[T], error) {
+	if finder, ok := res.db.(models.Finder[T]); ok {
+		for _, f := range filters {
+			entries, err := finder.FindBy(ctx, f.field, f.value)
+			if errors.Is(err, models.ErrNotIndexed) {
+				continue
+			}
+			return entries, err
+		}
+	}
+
+	return res.list(ctx)
+}
+
+type CountResponse struct {
+	Count int `json:"count"`
+}
+
+func (res *Resource[T]) handleCount() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		items, ok := res.collect(w, r, nil)
+		if !ok {
+			return
+		}
+
+		writeJSON(w, r, http.StatusOK, CountResponse{Count: len(items)})
+	}
+}
+
+// handleHeadList answers HEAD on the collection with just the number of records the filters
+// select, in an X-Total-Count header.
+func (res *Resource[T]) handleHeadList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		items, ok := res.collect(w, r, nil)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(items)))
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (res *Resource[T]) handleFindById() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parsedID, err := parseURLID(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidID, err.Error())
+			return
+		}
+
+		fields, err := parseFields[T](r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidQuery, err.Error())
+			return
+		}
+
+		expand, err := res.parseExpand(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidQuery, err.Error())
+			return
+		}
+
+		value, err := res.db.Get(r.Context(), parsedID)
+		if err != nil {
+			res.writeStoreError(w, r, err)
+			return
+		}
+		if isDeleted(value) {
+			res.notFound(w, r)
+			return
+		}
+
+		resp, err := NewResponse(parsedID, value)
+		if err != nil {
+			// a backend handing back nil without ErrNotFound is a bug, not a missing record
+			res.writeStoreError(w, r, err)
+			return
+		}
+		resp.fields = fields
+		resp.idFormat = res.idFormat
+
+		if err := res.expand(r.Context(), &resp, expand); err != nil {
+			res.writeStoreError(w, r, err)
+			return
+		}
+
+		writeEntity(w, r, http.StatusOK, resp)
+	}
+}
+
+// handleExists answers HEAD with just the status a GET would have, so clients can check a
+// record exists without fetching it.
+func (res *Resource[T]) handleExists() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parsedID, err := parseURLID(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		value, err := res.db.Get(r.Context(), parsedID)
+		switch {
+		case errors.Is(err, models.ErrNotFound), err == nil && isDeleted(value):
+			w.WriteHeader(http.StatusNotFound)
+		case err != nil:
+			res.logger.ErrorContext(r.Context(), "storage error", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+}
+
+// handleInsert creates a record. Clients may supply its ID, in the body or as an Idempotency-Key
+// header, so retrying a POST returns the record the first attempt created instead of a duplicate.
+func (res *Resource[T]) handleInsert() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		body, err := requestBody(r)
+		if err != nil {
+			writeDecodeError(w, r, err)
+			return
+		}
+
+		value, bodyID, err := decodeBodyWithID[T](body)
+		if err != nil {
+			writeDecodeError(w, r, err)
+			return
+		}
+
+		id, err := clientID(r, bodyID)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidID, err.Error())
+			return
+		}
+
+		expirer, ttl, err := res.parseTTL(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidQuery, err.Error())
+			return
+		}
+
+		dry, err := dryRun(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidQuery, err.Error())
+			return
+		}
+
+		if id != uuid.Nil && res.writeExisting(w, r, id) {
+			return
+		}
+
+		if errs := res.validate(value); len(errs) > 0 {
+			writeValidationErrors(w, r, errs)
+			return
+		}
+
+		// a concurrent retry creating the record first mustn't count as a clash with it; the
+		// insert below finds it taken and answers with it instead
+		if err := res.checkUnique(r.Context(), id, value); err != nil {
+			res.writeStoreError(w, r, err)
+			return
+		}
+
+		prepareInsert(value, time.Now().UTC())
+
+		if id == uuid.Nil {
+			id = res.ids.New()
+		}
+
+		if dry {
+			writeDryRun(w, r, res.response(id, value))
+			return
+		}
+
+		if err := res.db.Insert(r.Context(), id, value); err != nil {
+			// a concurrent retry may have created it between the lookup above and this insert
+			if errors.Is(err, models.ErrConflict) && res.writeExisting(w, r, id) {
+				return
+			}
+			res.writeStoreError(w, r, err)
+			return
+		}
+
+		if ttl > 0 {
+			if err := expirer.Expire(r.Context(), id, ttl); err != nil {
+				res.writeStoreError(w, r, err)
+				return
+			}
+		}
+
+		created := res.response(id, value)
+		res.emit(r.Context(), EventCreate, created)
+
+		w.Header().Set("Location", res.location(id))
+		writeEntity(w, r, http.StatusCreated, created)
+	}
+}
+
+// parseTTL reads the ttl query parameter of an insert, like ?ttl=10m, for stores whose records
+// can expire. A zero ttl means none was given and the store's default applies.
+func (res *Resource[T]) parseTTL(r *http.Request) (models.Expirer, time.Duration, error) {
+	raw := r.URL.Query().Get("ttl")
+	if raw == "" {
+		return nil, 0, nil
+	}
+
+	expirer, ok := res.db.(models.Expirer)
+	if !ok {
+		return nil, 0, errors.New("ttl isn't supported by this store")
+	}
+
+	ttl, err := time.ParseDuration(raw)
+	if err != nil || ttl <= 0 {
+		return nil, 0, errors.New("ttl must be a positive duration like 30s or 10m")
+	}
+
+	return expirer, ttl, nil
+}
+
+// parseURLID reads the id path parameter. Some mount setups leave it empty, which uuid.Parse
+// would report confusingly, so a blank one gets its own message.
+func parseURLID(r *http.Request) (uuid.UUID, error) {
+	raw := chi.URLParam(r, "id")
+	if strings.TrimSpace(raw) == "" {
+		return uuid.Nil, errors.New("Missing ID")
+	}
+
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, errors.New("Invalid ID")
+	}
+
+	return id, nil
+}
+
+// response pairs id and value the way the resource writes them out.
+func (res *Resource[T]) response(id uuid.UUID, value T) Response[T] {
+	return Response[T]{ID: id, Model: value, idFormat: res.idFormat}
+}
+
+// location is the URL a record can be fetched from, for Location headers.
+func (res *Resource[T]) location(id uuid.UUID) string {
+	return strings.TrimSuffix(res.prefix, "/") + "/" + formatID(res.idFormat, id)
+}
+
+// writeExisting answers a repeated insert with the record already stored under id, reporting
+// whether it wrote a response. A soft-deleted record can't be recreated, so that's a conflict.
+func (res *Resource[T]) writeExisting(w http.ResponseWriter, r *http.Request, id uuid.UUID) bool {
+	existing, err := res.db.Get(r.Context(), id)
+	if errors.Is(err, models.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		res.writeStoreError(w, r, err)
+		return true
+	}
+
+	if isDeleted(existing) {
+		writeError(w, http.StatusConflict, ErrCodeConflict, fmt.Sprintf("a deleted %s already has this id", strings.ToLower(res.name)))
+		return true
+	}
+
+	writeEntity(w, r, http.StatusOK, res.response(id, existing))
+	return true
+}
+
+type BatchItemError struct {
+	Index  int                     `json:"index"`
+	Errors models.ValidationErrors `json:"errors"`
+}
+
+type BatchErrorResponse struct {
+	Errors []BatchItemError `json:"errors"`
+}
+
+// handleBatchInsert creates every item in the body or none of them. All items are validated
+// up front, and if the store fails part way the items already written are removed again.
+func (res *Resource[T]) handleBatchInsert() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		body, err := requestBody(r)
+		if err != nil {
+			writeDecodeError(w, r, err)
+			return
+		}
+
+		values, err := decodeBody[[]T](body)
+		if err != nil {
+			writeDecodeError(w, r, err)
+			return
+		}
+		if len(values) == 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidBody, "Batch must contain at least one item")
+			return
+		}
+
+		dry, err := dryRun(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidQuery, err.Error())
+			return
+		}
+
+		var itemErrors []BatchItemError
+		seenKeys := map[models.UniqueKey]int{}
+		for i, value := range values {
+			errs := res.validate(value)
+
+			if len(errs) == 0 {
+				var conflict *models.ConflictError
+				if err := res.checkUnique(r.Context(), uuid.Nil, value); errors.As(err, &conflict) {
+					errs.Add(conflict.Field, "already exists")
+				} else if err != nil {
+					res.writeStoreError(w, r, err)
+					return
+				}
+			}
+
+			// the store can't catch duplicates between items that aren't written yet
+			if len(errs) == 0 {
+				for _, key := range res.uniqueKeys(value) {
+					if first, seen := seenKeys[key]; seen {
+						errs.Add(key.Field, fmt.Sprintf("duplicates item %d", first))
+					} else {
+						seenKeys[key] = i
+					}
+				}
+			}
+
+			if len(errs) > 0 {
+				itemErrors = append(itemErrors, BatchItemError{Index: i, Errors: errs})
+			}
+		}
+		if len(itemErrors) > 0 {
+			writeJSON(w, r, http.StatusUnprocessableEntity, BatchErrorResponse{Errors: itemErrors})
+			return
+		}
+
+		now := time.Now().UTC()
+		created := make([]Response[T], 0, len(values))
+		for _, value := range values {
+			prepareInsert(value, now)
+
+			id := res.ids.New()
+			if dry {
+				created = append(created, res.response(id, value))
+				continue
+			}
+			if err := res.db.Insert(r.Context(), id, value); err != nil {
+				// the insert may have failed because the request was cancelled, which mustn't stop the cleanup
+				cleanup := context.WithoutCancel(r.Context())
+				for _, done := range created {
+					if err := res.db.Delete(cleanup, done.ID); err != nil {
+						res.logger.ErrorContext(r.Context(), "failed to roll back batch insert", "id", done.ID, "error", err)
+					}
+				}
+				res.writeStoreError(w, r, err)
+				return
+			}
+
+			created = append(created, res.response(id, value))
+		}
+		if dry {
+			writeDryRun(w, r, created)
+			return
+		}
+		for _, record := range created {
+			res.emit(r.Context(), EventCreate, record)
+		}
+
+		writeJSON(w, r, http.StatusCreated, created)
+	}
+}
+
+func (res *Resource[T]) handleUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		parsedID, err := parseURLID(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidID, err.Error())
+			return
+		}
+
+		body, err := requestBody(r)
+		if err != nil {
+			writeDecodeError(w, r, err)
+			return
+		}
+
+		value, bodyID, err := decodeBodyWithID[T](body)
+		if err != nil {
+			writeDecodeError(w, r, err)
+			return
+		}
+		if !bodyIDMatches(w, bodyID, parsedID) {
+			return
+		}
+
+		dry, err := dryRun(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidQuery, err.Error())
+			return
+		}
+
+		if errs := res.validate(value); len(errs) > 0 {
+			writeValidationErrors(w, r, errs)
+			return
+		}
+
+		if err := res.checkUnique(r.Context(), parsedID, value); err != nil {
+			res.writeStoreError(w, r, err)
+			return
+		}
+
+		current, err := res.db.Get(r.Context(), parsedID)
+		if errors.Is(err, models.ErrNotFound) && res.putCreates {
+			res.createAt(w, r, parsedID, value, dry)
+			return
+		}
+		if err != nil {
+			res.writeStoreError(w, r, err)
+			return
+		}
+		if isDeleted(current) {
+			res.notFound(w, r)
+			return
+		}
+
+		// a timestamp precondition stands in for the version PUT otherwise requires
+		unmodifiedGiven, ok := checkUnmodifiedSince(w, r, current)
+		if !ok {
+			return
+		}
+
+		if !res.checkVersion(w, r, parsedID, value, current, !unmodifiedGiven) {
+			return
+		}
+
+		clearDeleted(value)
+		bumpVersion(value, current)
+		// a full replace still keeps the original creation time
+		if stamped, ok := any(value).(Timestamped); ok {
+			stamped.SetCreatedAt(any(current).(Timestamped).GetCreatedAt())
+			stamped.SetUpdatedAt(time.Now().UTC())
+		}
+
+		if dry {
+			writeDryRun(w, r, res.response(parsedID, value))
+			return
+		}
+
+		if err := res.db.Update(r.Context(), parsedID, value); err != nil {
+			res.writeStoreError(w, r, err)
+			return
+		}
+
+		updated := res.response(parsedID, value)
+		res.emitUpdate(r.Context(), current, updated)
+
+		writeEntity(w, r, http.StatusOK, updated)
+	}
+}
+
+// createAt answers a PUT to an ID nothing is stored under by creating the record there, unless
+// dry is set. Preconditions can only refer to an existing record, so any If-Match fails.
+func (res *Resource[T]) createAt(w http.ResponseWriter, r *http.Request, id uuid.UUID, value T, dry bool) {
+	if r.Header.Get("If-Match") != "" {
+		writeError(w, http.StatusPreconditionFailed, ErrCodePreconditionFailed, fmt.Sprintf("No %s exists with this id to match", strings.ToLower(res.name)))
+		return
+	}
+
+	prepareInsert(value, time.Now().UTC())
+	if dry {
+		writeDryRun(w, r, res.response(id, value))
+		return
+	}
+	if err := res.db.Insert(r.Context(), id, value); err != nil {
+		res.writeStoreError(w, r, err)
+		return
+	}
+
+	created := res.response(id, value)
+	res.emit(r.Context(), EventCreate, created)
+
+	w.Header().Set("Location", res.location(id))
+	writeEntity(w, r, http.StatusCreated, created)
+}
+
+func (res *Resource[T]) handlePatch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		parsedID, err := parseURLID(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidID, err.Error())
+			return
+		}
+
+		// savePatched reads it again, but a bad value should fail before the body is looked at
+		if _, err := dryRun(r); err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidQuery, err.Error())
+			return
+		}
+
+		switch patchFormat(r) {
+		case contentTypeMergePatch:
+			res.handleMergePatch(w, r, parsedID)
+			return
+		case contentTypeJSONPatch:
+			res.handleJSONPatch(w, r, parsedID)
+			return
+		}
+
+		// fields left out of the body stay nil, which is how absent fields are told apart from empty ones
+		body, err := requestBody(r)
+		if err != nil {
+			writeDecodeError(w, r, err)
+			return
+		}
+
+		patch, bodyID, err := decodeBodyWithID[T](body)
+		if err != nil && !errors.Is(err, io.EOF) {
+			writeDecodeError(w, r, err)
+			return
+		}
+		if !bodyIDMatches(w, bodyID, parsedID) {
+			return
+		}
+
+		current, ok := res.getLive(w, r, parsedID)
+		if !ok {
+			return
+		}
+
+		if !res.checkVersion(w, r, parsedID, patch, current, false) {
+			return
+		}
+
+		value := mergeNonNil(current, patch)
+		clearDeleted(value)
+
+		res.savePatched(w, r, parsedID, current, value)
+	}
+}
+
+// getLive fetches the record under id, answering 404 itself when it's missing or soft-deleted.
+func (res *Resource[T]) getLive(w http.ResponseWriter, r *http.Request, id uuid.UUID) (T, bool) {
+	current, err := res.db.Get(r.Context(), id)
+	if err != nil {
+		res.writeStoreError(w, r, err)
+		return current, false
+	}
+	if isDeleted(current) {
+		res.notFound(w, r)
+		return current, false
+	}
+
+	return current, true
+}
+
+// savePatched validates and stores value, the patched version of current, and answers with it.
+// A dry run stops short of storing it, and a patch that changes nothing answers with current
+// without writing, so its version, timestamp and ETag stay as they were.
+func (res *Resource[T]) savePatched(w http.ResponseWriter, r *http.Request, id uuid.UUID, current, value T) {
+	if reflect.DeepEqual(value, current) {
+		writeEntity(w, r, http.StatusOK, res.response(id, current))
+		return
+	}
+
+	bumpVersion(value, current)
+
+	if errs := res.validate(value); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	if err := res.checkUnique(r.Context(), id, value); err != nil {
+		res.writeStoreError(w, r, err)
+		return
+	}
+	if stamped, ok := any(value).(Timestamped); ok {
+		stamped.SetUpdatedAt(time.Now().UTC())
+	}
+
+	if dry, _ := dryRun(r); dry {
+		writeDryRun(w, r, res.response(id, value))
+		return
+	}
+
+	if err := res.db.Update(r.Context(), id, value); err != nil {
+		res.writeStoreError(w, r, err)
+		return
+	}
+
+	updated := res.response(id, value)
+	res.emitUpdate(r.Context(), current, updated)
+
+	writeEntity(w, r, http.StatusOK, updated)
+}
+
+// handleRestore brings a soft-deleted record back. Only deleted records can be restored, so a
+// live or unknown id is a 404, and one whose unique key was taken in the meantime is a 409.
+func (res *Resource[T]) handleRestore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parsedID, err := parseURLID(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidID, err.Error())
+			return
+		}
+
+		current, err := res.db.Get(r.Context(), parsedID)
+		if err != nil {
+			res.writeStoreError(w, r, err)
+			return
+		}
+		if !isDeleted(current) {
+			writeError(w, http.StatusNotFound, ErrCodeNotFound, "No deleted "+strings.ToLower(res.name)+" with this id")
+			return
+		}
+
+		value := clone(current)
+		clearDeleted(value)
+		bumpVersion(value, current)
+		if stamped, ok := any(value).(Timestamped); ok {
+			stamped.SetUpdatedAt(time.Now().UTC())
+		}
+
+		if err := res.checkUnique(r.Context(), parsedID, value); err != nil {
+			res.writeStoreError(w, r, err)
+			return
+		}
+
+		if err := res.db.Update(r.Context(), parsedID, value); err != nil {
+			res.writeStoreError(w, r, err)
+			return
+		}
+
+		updated := res.response(parsedID, value)
+		res.emitUpdate(r.Context(), current, updated)
+
+		writeEntity(w, r, http.StatusOK, updated)
+	}
+}
+
+// handleClear permanently removes every record, soft-deleted or not. It's meant for resetting
+// test fixtures, so it's only routed when Config.AllowClear is set.
+func (res *Resource[T]) handleClear() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := res.clear(r.Context()); err != nil {
+			res.writeStoreError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (res *Resource[T]) handleDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		parsedID, err := parseURLID(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidID, err.Error())
+			return
+		}
+
+		current, err := res.db.Get(r.Context(), parsedID)
+		if err == nil && isDeleted(current) {
+			err = models.ErrNotFound
+		}
+		if err == nil {
+			if _, ok := checkUnmodifiedSince(w, r, current); !ok {
+				return
+			}
+			if header := r.Header.Get("If-Match"); header != "" && !res.checkIfMatch(w, r, parsedID, header, current) {
+				return
+			}
+			err = res.remove(r.Context(), parsedID, current)
+		}
+
+		// a retry of a delete that went through finds nothing left, which is what it asked for
+		if errors.Is(err, models.ErrNotFound) && res.idempotentDelete {
+			err = nil
+		}
+		if err != nil {
+			res.writeStoreError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// remove deletes the live record current stored under id. Soft-deletable models are only
+// marked, so the record can still be audited.
+func (res *Resource[T]) remove(ctx context.Context, id uuid.UUID, current T) error {
+	deleted := current
+	if _, ok := any(current).(SoftDeletable); ok {
+		deleted = clone(current)
+		now := time.Now().UTC()
+		any(deleted).(SoftDeletable).SetDeletedAt(&now)
+		bumpVersion(deleted, current)
+
+		if err := res.db.Update(ctx, id, deleted); err != nil {
+			return err
+		}
+	} else if err := res.db.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	res.emit(ctx, EventDelete, res.response(id, deleted))
+	return nil
+}
+
+const (
+	BatchDeleteDeleted   = "deleted"
+	BatchDeleteNotFound  = "not_found"
+	BatchDeleteInvalidID = "invalid_id"
+	BatchDeleteFailed    = "error"
+)
+
+type BatchDeleteResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+type BatchDeleteResponse struct {
+	Results []BatchDeleteResult `json:"results"`
+}
+
+// handleBatchDelete deletes every ID in the body it can and answers 207 with the outcome of
+// each, in the order given. Unlike handleBatchInsert, one missing ID doesn't stop the rest.
+func (res *Resource[T]) handleBatchDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		body, err := requestBody(r)
+		if err != nil {
+			writeDecodeError(w, r, err)
+			return
+		}
+
+		ids, err := decodeBody[[]string](body)
+		if err != nil {
+			writeDecodeError(w, r, err)
+			return
+		}
+		if len(ids) == 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidBody, "Batch must contain at least one ID")
+			return
+		}
+
+		results := make([]BatchDeleteResult, len(ids))
+		for i, id := range ids {
+			results[i] = BatchDeleteResult{ID: id, Status: res.deleteOne(r, id)}
+		}
+
+		writeJSON(w, r, http.StatusMultiStatus, BatchDeleteResponse{Results: results})
+	}
+}
+
+// deleteOne deletes one record of a batch, returning its outcome instead of writing a response.
+func (res *Resource[T]) deleteOne(r *http.Request, id string) string {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return BatchDeleteInvalidID
+	}
+
+	current, err := res.db.Get(r.Context(), parsedID)
+	if err == nil && isDeleted(current) {
+		err = models.ErrNotFound
+	}
+	if err == nil {
+		err = res.remove(r.Context(), parsedID, current)
+	}
+
+	switch {
+	case err == nil:
+		return BatchDeleteDeleted
+	case errors.Is(err, models.ErrNotFound):
+		return BatchDeleteNotFound
+	default:
+		res.logger.ErrorContext(r.Context(), "batch delete failed", "id", parsedID, "error", err)
+		return BatchDeleteFailed
+	}
+}
@@ -0,0 +1,542 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"rocketseat/models"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestInsertThenGet(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	created := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	got := s.GetUser(created.ID)
+
+	if *got.FirstName != "Jane" || *got.LastName != "Doe" || *got.Email != "jane@example.com" {
+		t.Errorf("got %+v, want the inserted user", got.User)
+	}
+	if got.Version != 1 {
+		t.Errorf("got version %d, want 1", got.Version)
+	}
+}
+
+func TestUpdateReplacesUser(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+
+	user.FirstName = ptr("Janet")
+	user.Biography = ptr("Rewritten")
+	updated := s.UpdateUser(user.ID, user.User)
+
+	if *updated.FirstName != "Janet" || *updated.Biography != "Rewritten" {
+		t.Errorf("got %+v, want the new fields", updated.User)
+	}
+	if got := s.GetUser(user.ID); *got.FirstName != "Janet" || got.Version != 2 {
+		t.Errorf("got %+v, want Janet at version 2", got.User)
+	}
+}
+
+func TestListReturnsEveryUser(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	jane := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	john := s.InsertUser(apitest.NewUser("John", "Roe", "john@example.com"))
+
+	users := s.ListUsers("")
+	if len(users) != 2 {
+		t.Fatalf("got %d users, want 2", len(users))
+	}
+	ids := map[string]bool{users[0].ID.String(): true, users[1].ID.String(): true}
+	if !ids[jane.ID.String()] || !ids[john.ID.String()] {
+		t.Errorf("got %v, want Jane and John", ids)
+	}
+}
+
+func TestDeleteRemovesUser(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+
+	s.DeleteUser(user.ID)
+
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/users/"+user.ID.String(), nil), http.StatusNotFound)
+	if users := s.ListUsers(""); len(users) != 0 {
+		t.Errorf("got %d users listed, want none", len(users))
+	}
+}
+
+func TestMalformedIDAnswersOneError(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		rec := s.Do(method, "/users/not-a-uuid", nil)
+
+		dec := json.NewDecoder(rec.Body)
+		var body api.ErrorResponse
+		if err := dec.Decode(&body); err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		if rec.Code != http.StatusBadRequest || body.Error.Code != api.ErrCodeInvalidID {
+			t.Errorf("%s: got %d %q, want 400 %q", method, rec.Code, body.Error.Code, api.ErrCodeInvalidID)
+		}
+		// a handler that kept going would have written its own response after the error
+		if rest, _ := io.ReadAll(dec.Buffered()); len(bytes.TrimSpace(rest)) > 0 || rec.Body.Len() > 0 {
+			t.Errorf("%s: got more after the error: %s", method, rest)
+		}
+	}
+}
+
+func TestConcurrentInsertsAndDeletes(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	var ids []uuid.UUID
+	for i := range 100 {
+		ids = append(ids, s.InsertUser(apitest.NewUser("Jane", "Doe", fmt.Sprintf("jane%d@example.com", i))).ID)
+	}
+
+	// delete the first 100 while inserting 100 more, all at once
+	statuses := make(chan int, 200)
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			statuses <- s.Do(http.MethodDelete, "/users/"+id.String(), nil).Code
+		}()
+		go func() {
+			defer wg.Done()
+			statuses <- s.Do(http.MethodPost, "/users", apitest.NewUser("John", "Roe", fmt.Sprintf("john%d@example.com", i))).Code
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	for status := range statuses {
+		if status != http.StatusNoContent && status != http.StatusCreated {
+			t.Errorf("got status %d", status)
+		}
+	}
+
+	all, err := s.Store.GetAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := 0
+	for _, user := range all {
+		if user.GetDeletedAt() == nil {
+			live++
+		}
+	}
+	if live != 100 {
+		t.Errorf("got %d live users, want 100", live)
+	}
+	if users := s.ListUsers("limit=1000"); len(users) != 100 {
+		t.Errorf("got %d users listed, want 100", len(users))
+	}
+}
+
+func TestErrorsAreJSONEnvelopes(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	rec := s.Do(http.MethodGet, "/users/"+uuid.NewString(), nil)
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("got Content-Type %q, want application/json", got)
+	}
+	body := apitest.DecodeError(t, rec, http.StatusNotFound)
+	if body.Code != api.ErrCodeNotFound || body.Message == "" {
+		t.Errorf("got %+v, want a not_found code and a message", body)
+	}
+}
+
+func TestSuccessfulResponsesAreJSON(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	for _, rec := range []*httptest.ResponseRecorder{
+		s.Do(http.MethodPost, "/users", apitest.NewUser("Jane", "Doe", "jane@example.com")),
+		s.Do(http.MethodGet, "/users", nil),
+	} {
+		if got := rec.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("got Content-Type %q, want application/json", got)
+		}
+	}
+}
+
+func TestUpdateMovesUpdatedAt(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	if !user.UpdatedAt.Equal(user.CreatedAt) {
+		t.Errorf("got updated_at %v on insert, want created_at %v", user.UpdatedAt, user.CreatedAt)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	user.FirstName = ptr("Janet")
+	updated := s.UpdateUser(user.ID, user.User)
+
+	if !updated.UpdatedAt.After(updated.CreatedAt) {
+		t.Errorf("got updated_at %v, want it after created_at %v", updated.UpdatedAt, updated.CreatedAt)
+	}
+	if !updated.CreatedAt.Equal(user.CreatedAt) {
+		t.Errorf("got created_at %v, want it kept at %v", updated.CreatedAt, user.CreatedAt)
+	}
+}
+
+func TestDeletedUsersOnlyListedWhenAsked(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	jane := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	s.InsertUser(apitest.NewUser("John", "Roe", "john@example.com"))
+	s.DeleteUser(jane.ID)
+
+	if users := s.ListUsers(""); len(users) != 1 || users[0].ID == jane.ID {
+		t.Errorf("got %d users with Jane among them, want only John", len(users))
+	}
+
+	users := s.ListUsers("include_deleted=true")
+	if len(users) != 2 {
+		t.Fatalf("got %d users with include_deleted, want 2", len(users))
+	}
+	for _, user := range users {
+		if user.ID == jane.ID && user.DeletedAt == nil {
+			t.Error("got Jane without deleted_at")
+		}
+	}
+}
+
+func TestMethodNotAllowedListsAllowedMethods(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	rec := s.Do(http.MethodPost, "/users/"+uuid.NewString(), nil)
+	if got := apitest.DecodeError(t, rec, http.StatusMethodNotAllowed); got.Code != api.ErrCodeMethodNotAllowed {
+		t.Errorf("got code %q, want %q", got.Code, api.ErrCodeMethodNotAllowed)
+	}
+
+	allowed := strings.Split(rec.Header().Get("Allow"), ", ")
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		if !slices.Contains(allowed, method) {
+			t.Errorf("got Allow %v, want %s among them", allowed, method)
+		}
+	}
+	if slices.Contains(allowed, http.MethodPost) {
+		t.Errorf("got Allow %v, want POST left out", allowed)
+	}
+}
+
+func TestClearRemovesEveryUser(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.AllowClear = true
+	s := apitest.NewTestServerWithConfig(t, cfg)
+	for i := range 3 {
+		s.InsertUser(apitest.NewUser("Jane", "Doe", fmt.Sprintf("jane%d@example.com", i)))
+	}
+
+	apitest.ExpectStatus(t, s.Do(http.MethodDelete, "/users", nil), http.StatusNoContent)
+
+	if users := s.ListUsers("include_deleted=true"); len(users) != 0 {
+		t.Errorf("got %d users after clearing, want none", len(users))
+	}
+}
+
+func TestClearIsOffByDefault(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+
+	apitest.ExpectStatus(t, s.Do(http.MethodDelete, "/users", nil), http.StatusMethodNotAllowed)
+	if users := s.ListUsers(""); len(users) != 1 {
+		t.Errorf("got %d users, want the one inserted", len(users))
+	}
+}
+
+func TestInsertAnswersWithLocation(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	rec := s.Do(http.MethodPost, "/users", apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	apitest.ExpectStatus(t, rec, http.StatusCreated)
+	var created apitest.User
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+
+	location := rec.Header().Get("Location")
+	if want := "/users/" + created.ID.String(); location != want {
+		t.Errorf("got Location %q, want %q", location, want)
+	}
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, location, nil), http.StatusOK)
+}
+
+func TestUpdateChecksBodyID(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	path := "/users/" + user.ID.String()
+
+	body := map[string]any{
+		"id": uuid.NewString(), "first_name": "Janet", "last_name": "Doe",
+		"biography": "Moved", "email": "jane@example.com", "version": user.Version,
+	}
+	if got := apitest.DecodeError(t, s.Do(http.MethodPut, path, body), http.StatusBadRequest); got.Code != api.ErrCodeInvalidID {
+		t.Errorf("got code %q for a mismatched id, want %q", got.Code, api.ErrCodeInvalidID)
+	}
+
+	body["id"] = user.ID.String()
+	apitest.ExpectStatus(t, s.Do(http.MethodPut, path, body), http.StatusOK)
+
+	delete(body, "id")
+	body["version"] = user.Version + 1
+	apitest.ExpectStatus(t, s.Do(http.MethodPut, path, body), http.StatusOK)
+}
+
+func TestRestoreBringsBackDeletedUser(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	s.DeleteUser(user.ID)
+
+	rec := s.Do(http.MethodPost, "/users/"+user.ID.String()+"/restore", nil)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+
+	if got := s.GetUser(user.ID); got.DeletedAt != nil || *got.FirstName != "Jane" {
+		t.Errorf("got %+v, want Jane back without deleted_at", got.User)
+	}
+}
+
+func TestRestoreOfLiveUserIsNotFound(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+
+	rec := s.Do(http.MethodPost, "/users/"+user.ID.String()+"/restore", nil)
+	if got := apitest.DecodeError(t, rec, http.StatusNotFound); got.Code != api.ErrCodeNotFound {
+		t.Errorf("got code %q, want %q", got.Code, api.ErrCodeNotFound)
+	}
+}
+
+func TestHeadTellsWhetherUserExists(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+
+	for id, want := range map[uuid.UUID]int{user.ID: http.StatusOK, uuid.New(): http.StatusNotFound} {
+		rec := s.Do(http.MethodHead, "/users/"+id.String(), nil)
+		apitest.ExpectStatus(t, rec, want)
+		if rec.Body.Len() != 0 {
+			t.Errorf("got a %d byte body with the %d, want none", rec.Body.Len(), want)
+		}
+	}
+}
+
+func TestResponseRefusesNilUser(t *testing.T) {
+	id := uuid.New()
+
+	if _, err := api.NewUserResponse(id, nil); err == nil {
+		t.Error("got no error building a response for a nil user")
+	}
+	if _, err := json.Marshal(api.UserResponse{ID: id}); err == nil {
+		t.Error("got no error marshaling a response without a user")
+	}
+
+	resp, err := api.NewUserResponse(id, &models.User{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte(`{"id":"`+id.String()+`"`)) {
+		t.Errorf("got %s, want the id first", data)
+	}
+}
+
+func TestBasePathMountsTheAPI(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.BasePath = "/api/v1"
+	s := apitest.NewTestServerWithConfig(t, cfg)
+
+	rec := s.Do(http.MethodPost, "/api/v1/users", apitest.NewUser("Ada", "Lovelace", "ada@example.com"))
+	apitest.ExpectStatus(t, rec, http.StatusCreated)
+	location := rec.Header().Get("Location")
+	if !strings.HasPrefix(location, "/api/v1/users/") {
+		t.Fatalf("got Location %q, want it under /api/v1/users", location)
+	}
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, location, nil), http.StatusOK)
+
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/users", nil), http.StatusNotFound)
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/healthz", nil), http.StatusOK)
+}
+
+func TestInsertWithTTLExpires(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.Logger = discardLogger()
+	handler := api.NewHandler(models.NewTTLStore[*models.User](0), cfg)
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(newJSONRequest(t, http.MethodPost, "/users?ttl=20ms", apitest.NewUser("Jane", "Doe", "jane@example.com")))
+	apitest.ExpectStatus(t, rec, http.StatusCreated)
+	location := rec.Header().Get("Location")
+	apitest.ExpectStatus(t, serve(httptest.NewRequest(http.MethodGet, location, nil)), http.StatusOK)
+
+	time.Sleep(40 * time.Millisecond)
+	apitest.ExpectStatus(t, serve(httptest.NewRequest(http.MethodGet, location, nil)), http.StatusNotFound)
+
+	rec = serve(newJSONRequest(t, http.MethodPost, "/users?ttl=soon", apitest.NewUser("John", "Roe", "john@example.com")))
+	apitest.ExpectStatus(t, rec, http.StatusBadRequest)
+}
+
+func TestPutCreatesThenReplaces(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.PutCreates = true
+	s := apitest.NewTestServerWithConfig(t, cfg)
+	id := uuid.New()
+	path := "/users/" + id.String()
+
+	rec := s.Do(http.MethodPut, path, apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	apitest.ExpectStatus(t, rec, http.StatusCreated)
+	if got := rec.Header().Get("Location"); got != path {
+		t.Errorf("got Location %q, want %q", got, path)
+	}
+	created := s.GetUser(id)
+	if *created.FirstName != "Jane" {
+		t.Errorf("got first name %q, want Jane", *created.FirstName)
+	}
+
+	created.FirstName = ptr("Janet")
+	apitest.ExpectStatus(t, s.Do(http.MethodPut, path, created.User), http.StatusOK)
+	if got := s.GetUser(id); *got.FirstName != "Janet" {
+		t.Errorf("got first name %q, want Janet", *got.FirstName)
+	}
+
+	apitest.ExpectStatus(t, s.Do(http.MethodPut, "/users/not-a-uuid", created.User), http.StatusBadRequest)
+
+	strict := apitest.NewTestServer(t)
+	apitest.ExpectStatus(t, strict.Do(http.MethodPut, path, apitest.NewUser("Jane", "Doe", "jane@example.com")), http.StatusNotFound)
+}
+
+func TestNotFoundNamesTheID(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	id := uuid.NewString()
+
+	got := apitest.DecodeError(t, s.Do(http.MethodGet, "/users/"+id, nil), http.StatusNotFound)
+	want := api.ErrorDetail{Code: api.ErrCodeNotFound, Message: got.Message, Resource: "user", ID: id}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestBlankIDIsMissing(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := apitest.NewUser("Jane", "Doe", "jane@example.com")
+
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		for _, id := range []string{"%20", "%09%20"} {
+			rec := s.Do(method, "/users/"+id, user)
+			got := apitest.DecodeError(t, rec, http.StatusBadRequest)
+			if got.Code != api.ErrCodeInvalidID || got.Message != "Missing ID" {
+				t.Errorf("%s /users/%s: got %q %q, want %q \"Missing ID\"", method, id, got.Code, got.Message, api.ErrCodeInvalidID)
+			}
+		}
+	}
+}
+
+func TestTrailingSlash(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	apitest.ExpectStatus(t, s.Do(http.MethodPost, "/users/", apitest.NewUser("Jane", "Doe", "jane@example.com")), http.StatusCreated)
+	user := s.ListUsers("")[0]
+
+	for _, pair := range [][2]string{
+		{"/users", "/users/"},
+		{"/users/" + user.ID.String(), "/users/" + user.ID.String() + "/"},
+	} {
+		bare, slashed := s.Do(http.MethodGet, pair[0], nil), s.Do(http.MethodGet, pair[1], nil)
+		apitest.ExpectStatus(t, bare, http.StatusOK)
+		apitest.ExpectStatus(t, slashed, http.StatusOK)
+		if !bytes.Equal(bare.Body.Bytes(), slashed.Body.Bytes()) {
+			t.Errorf("got %s for %s and %s for %s, want the same", bare.Body, pair[0], slashed.Body, pair[1])
+		}
+	}
+
+	cfg := api.DefaultConfig()
+	cfg.TrailingSlash = api.TrailingSlashRedirect
+	redirecting := apitest.NewTestServerWithConfig(t, cfg)
+	rec := redirecting.Do(http.MethodGet, "/users/", nil)
+	apitest.ExpectStatus(t, rec, http.StatusMovedPermanently)
+	if got := rec.Header().Get("Location"); got != "/users" {
+		t.Errorf("got Location %q, want /users", got)
+	}
+}
+
+func TestDeletedFlagOnlyOnDeletedUsers(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	jane := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	john := s.InsertUser(apitest.NewUser("John", "Roe", "john@example.com"))
+	s.DeleteUser(jane.ID)
+
+	rec := s.Do(http.MethodGet, "/users?include_deleted=true", nil)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	var page struct {
+		Data []map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Data) != 2 {
+		t.Fatalf("got %d users, want 2", len(page.Data))
+	}
+	for _, user := range page.Data {
+		deleted, hasFlag := user["deleted"]
+		_, hasDeletedAt := user["deleted_at"]
+		switch user["id"] {
+		case jane.ID.String():
+			if deleted != true || !hasDeletedAt {
+				t.Errorf("got %v for Jane, want deleted and deleted_at", user)
+			}
+		case john.ID.String():
+			if hasFlag || hasDeletedAt {
+				t.Errorf("got %v for John, want neither deleted nor deleted_at", user)
+			}
+		}
+	}
+}
+
+func TestDeleteOfMissingUser(t *testing.T) {
+	path := "/users/" + uuid.NewString()
+
+	strict := apitest.NewTestServer(t)
+	if got := apitest.DecodeError(t, strict.Do(http.MethodDelete, path, nil), http.StatusNotFound); got.Code != api.ErrCodeNotFound {
+		t.Errorf("got code %q, want %q", got.Code, api.ErrCodeNotFound)
+	}
+
+	cfg := api.DefaultConfig()
+	cfg.IdempotentDelete = true
+	idempotent := apitest.NewTestServerWithConfig(t, cfg)
+	user := idempotent.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	for _, path := range []string{path, "/users/" + user.ID.String(), "/users/" + user.ID.String()} {
+		apitest.ExpectStatus(t, idempotent.Do(http.MethodDelete, path, nil), http.StatusNoContent)
+	}
+	apitest.ExpectStatus(t, idempotent.Do(http.MethodGet, "/users/"+user.ID.String(), nil), http.StatusNotFound)
+}
+
+func TestMaxRecordsRefusesTheNextInsert(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	s.Store.SetMaxRecords(2)
+	insertNamed(s, "Ann", "Bob")
+
+	rec := s.Do(http.MethodPost, "/users", apitest.NewUser("Cid", "Doe", "cid@example.com"))
+	got := apitest.DecodeError(t, rec, http.StatusInsufficientStorage)
+	if got.Code != api.ErrCodeStorageFull || !strings.Contains(got.Message, "at most 2") {
+		t.Errorf("got %q %q, want %q naming the cap", got.Code, got.Message, api.ErrCodeStorageFull)
+	}
+	if users := s.ListUsers(""); len(users) != 2 {
+		t.Errorf("got %d users, want 2", len(users))
+	}
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// modelSchema describes T as a JSON Schema object, following the same json tags encoding/json
+// uses. Fields can add to it with a schema tag: "readonly" for fields the API sets itself and
+// "format=..." for string formats, while min and max in validate tags become length limits.
+// Required fields are the ones an empty T fails validation on, so the schema can't drift from
+// what the handlers enforce.
+func modelSchema[T any]() map[string]any {
+	schema := typeSchema(reflect.TypeFor[T]())
+
+	var zero T
+	zero = newModel(zero)
+	var required []string
+	for _, fieldErr := range validate(zero) {
+		if fieldErr.Message == "required" && !slices.Contains(required, fieldErr.Field) {
+			required = append(required, fieldErr.Field)
+		}
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// newModel returns value itself, or a pointer to a fresh zero value when value is a nil pointer.
+func newModel[T any](value T) T {
+	v := reflect.ValueOf(&value).Elem()
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		v.Set(reflect.New(v.Type().Elem()))
+	}
+
+	return value
+}
+
+var uuidType = reflect.TypeFor[uuid.UUID]()
+
+func typeSchema(t reflect.Type) map[string]any {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var schema map[string]any
+	switch {
+	case t == timeType:
+		schema = map[string]any{"type": "string", "format": "date-time"}
+	case t == uuidType:
+		schema = map[string]any{"type": "string", "format": "uuid"}
+	default:
+		switch t.Kind() {
+		case reflect.String:
+			schema = map[string]any{"type": "string"}
+		case reflect.Bool:
+			schema = map[string]any{"type": "boolean"}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			schema = map[string]any{"type": "integer"}
+		case reflect.Float32, reflect.Float64:
+			schema = map[string]any{"type": "number"}
+		case reflect.Slice, reflect.Array:
+			schema = map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+		case reflect.Map:
+			schema = map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+		case reflect.Struct:
+			properties := map[string]any{}
+			collectProperties(t, properties)
+			schema = map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+		default:
+			schema = map[string]any{}
+		}
+	}
+
+	if nullable {
+		schema["nullable"] = true
+	}
+
+	return schema
+}
+
+// collectProperties adds t's JSON fields to properties, flattening embedded structs like encoding/json does.
+func collectProperties(t reflect.Type, properties map[string]any) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct && field.Type != timeType {
+			collectProperties(field.Type, properties)
+			continue
+		}
+
+		name := tag
+		if name == "" {
+			name = field.Name
+		}
+
+		property := typeSchema(field.Type)
+		for _, option := range strings.Split(field.Tag.Get("schema"), ",") {
+			switch key, value, _ := strings.Cut(strings.TrimSpace(option), "="); key {
+			case "readonly":
+				property["readOnly"] = true
+			case "format":
+				property["format"] = value
+			}
+		}
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(rule), "=")
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			switch key {
+			case "min":
+				property["minLength"] = n
+			case "max":
+				property["maxLength"] = n
+			}
+		}
+		properties[name] = property
+	}
+}
+
+// jsonSchemaDialect is the JSON Schema version GET /schema documents follow.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonSchema describes the records of res as a standalone JSON Schema document, for clients
+// checking bodies before sending them. It's modelSchema with the OpenAPI-only nullable turned
+// into a "null" type and the resource's length overrides applied.
+func (res *Resource[T]) jsonSchema() map[string]any {
+	schema := modelSchema[T]()
+	// models are pointers, but a body is always an object
+	delete(schema, "nullable")
+	required, _ := schema["required"].([]string)
+	properties, _ := schema["properties"].(map[string]any)
+	for field, property := range properties {
+		property := property.(map[string]any)
+		// required fields may be left out of the schema's nulls, since the API rejects them too
+		if slices.Contains(required, field) {
+			delete(property, "nullable")
+		}
+		if n, ok := res.maxLengths[field]; ok {
+			property["maxLength"] = n
+		}
+		toJSONSchema(property)
+	}
+	// clients may pick the ID of a new record themselves
+	properties["id"] = map[string]any{"type": "string", "format": "uuid"}
+
+	schema["$schema"] = jsonSchemaDialect
+	schema["title"] = res.name
+	return schema
+}
+
+// toJSONSchema rewrites the OpenAPI nullable keyword of schema and everything nested in it.
+func toJSONSchema(schema map[string]any) {
+	if nullable, _ := schema["nullable"].(bool); nullable {
+		delete(schema, "nullable")
+		if t, ok := schema["type"].(string); ok {
+			schema["type"] = []string{t, "null"}
+		}
+	}
+
+	if items, ok := schema["items"].(map[string]any); ok {
+		toJSONSchema(items)
+	}
+	if additional, ok := schema["additionalProperties"].(map[string]any); ok {
+		toJSONSchema(additional)
+	}
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		for _, property := range properties {
+			toJSONSchema(property.(map[string]any))
+		}
+	}
+}
+
+// handleSchema answers with jsonSchema, built on each request so it always matches the model.
+func (res *Resource[T]) handleSchema() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeEntity(w, r, http.StatusOK, res.jsonSchema())
+	}
+}
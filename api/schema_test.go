@@ -0,0 +1,51 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"rocketseat/api/apitest"
+	"slices"
+	"testing"
+)
+
+func TestUserSchemaMatchesTheModel(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	rec := s.Do(http.MethodGet, "/users/schema", nil)
+	apitest.ExpectStatus(t, rec, http.StatusOK)
+	var schema struct {
+		Type       string   `json:"type"`
+		Required   []string `json:"required"`
+		Properties map[string]struct {
+			Type      any    `json:"type"`
+			Format    string `json:"format"`
+			MaxLength int    `json:"maxLength"`
+			ReadOnly  bool   `json:"readOnly"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	if schema.Type != "object" {
+		t.Errorf("got type %q, want object", schema.Type)
+	}
+	if want := []string{"biography", "email", "first_name", "last_name"}; !slices.Equal(slices.Sorted(slices.Values(schema.Required)), want) {
+		t.Errorf("got required %v, want %v", schema.Required, want)
+	}
+
+	for name, maxLength := range map[string]int{"first_name": 100, "last_name": 100, "biography": 1000} {
+		if p := schema.Properties[name]; p.Type != "string" || p.MaxLength != maxLength {
+			t.Errorf("got %s %+v, want a string of at most %d", name, p, maxLength)
+		}
+	}
+	if p := schema.Properties["email"]; p.Type != "string" || p.Format != "email" {
+		t.Errorf("got email %+v, want an email string", p)
+	}
+	if p := schema.Properties["version"]; p.Type != "integer" {
+		t.Errorf("got version %+v, want an integer", p)
+	}
+	if p := schema.Properties["created_at"]; p.Format != "date-time" || !p.ReadOnly {
+		t.Errorf("got created_at %+v, want a read-only date-time", p)
+	}
+}
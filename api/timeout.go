@@ -0,0 +1,180 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var errRequestTimeout = errors.New("request timed out")
+
+// timeoutWriter buffers a handler's response so it can be thrown away if the deadline passes
+// before the handler finishes. Handlers that stream flush early instead, which sends what they
+// have so far and switches the writer to pass everything straight through.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	w        http.ResponseWriter
+	header   http.Header
+	buf      bytes.Buffer
+	status   int
+	timedOut bool
+	// streaming is set by the first Flush; from then on writes go straight to w
+	streaming bool
+	// disarm stops the deadline, reporting false if it has already passed
+	disarm func() bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.status != 0 {
+		return
+	}
+	tw.status = status
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	if tw.streaming {
+		return tw.w.Write(p)
+	}
+
+	return tw.buf.Write(p)
+}
+
+// Flush starts streaming. The deadline only covers the wait for the first byte, so once a
+// handler has started a response it may take as long as it needs to finish it.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return
+	}
+	if !tw.streaming {
+		if !tw.disarm() {
+			return
+		}
+		tw.streaming = true
+		tw.writeBuffered()
+	}
+
+	if flusher, ok := tw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.w
+}
+
+// writeBuffered sends the headers and whatever has been buffered. Callers must hold mu.
+func (tw *timeoutWriter) writeBuffered() {
+	for key, values := range tw.header {
+		tw.w.Header()[key] = values
+	}
+	if tw.status != 0 {
+		tw.w.WriteHeader(tw.status)
+	}
+	tw.w.Write(tw.buf.Bytes())
+	tw.buf.Reset()
+}
+
+// copyRouteContext returns a copy of rctx's route and URL parameters, for a handler that may
+// outlive the request: chi recycles rctx for the next request once this one is answered.
+func copyRouteContext(rctx *chi.Context) *chi.Context {
+	routed := chi.NewRouteContext()
+	routed.Routes = rctx.Routes
+	routed.RoutePath = rctx.RoutePath
+	routed.RouteMethod = rctx.RouteMethod
+	routed.URLParams.Keys = slices.Clone(rctx.URLParams.Keys)
+	routed.URLParams.Values = slices.Clone(rctx.URLParams.Values)
+	routed.RoutePatterns = slices.Clone(rctx.RoutePatterns)
+	return routed
+}
+
+// timeout cancels a request's context after d and answers 503 if the handler hasn't responded
+// by then. The handler runs in its own goroutine against a buffered writer, so one stuck on slow
+// storage can't hold the response up; anything it writes after the deadline is dropped. It has
+// to run after routing, as the routes' own middleware, since the handler only gets a copy of
+// the route context to read.
+func timeout(d time.Duration) func(http.Handler) http.Handler {
+	if d <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithCancelCause(r.Context())
+			defer cancel(nil)
+			if rctx := chi.RouteContext(ctx); rctx != nil {
+				ctx = context.WithValue(ctx, chi.RouteCtxKey, copyRouteContext(rctx))
+			}
+			timer := time.AfterFunc(d, func() { cancel(errRequestTimeout) })
+			defer timer.Stop()
+
+			tw := &timeoutWriter{w: w, header: w.Header().Clone(), disarm: timer.Stop}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- handlerPanic{value: p, stack: debug.Stack()}
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				// re-raise on the serving goroutine so the recoverer still sees it
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				if !tw.streaming {
+					tw.writeBuffered()
+				}
+			case <-ctx.Done():
+				tw.mu.Lock()
+				if tw.streaming {
+					// the client went away mid-stream; the handler is writing to w, so wait it out
+					tw.mu.Unlock()
+					select {
+					case p := <-panicked:
+						panic(p)
+					case <-done:
+					}
+					return
+				}
+				defer tw.mu.Unlock()
+
+				tw.timedOut = true
+				writeError(w, http.StatusServiceUnavailable, ErrCodeTimeout, "Request took too long to process")
+			}
+		})
+	}
+}
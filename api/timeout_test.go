@@ -0,0 +1,58 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"rocketseat/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// stuckStore is a store whose Get takes far longer than any request timeout.
+type stuckStore struct {
+	*models.Store[*models.User]
+}
+
+func (s stuckStore) Get(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	time.Sleep(200 * time.Millisecond)
+	return s.Store.Get(ctx, id)
+}
+
+func TestSlowStoreTimesOut(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.Logger = discardLogger()
+	cfg.RequestTimeout = 20 * time.Millisecond
+	handler := api.NewHandler(stuckStore{models.NewStore[*models.User]()}, cfg)
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/"+uuid.NewString(), nil))
+
+	if got := apitest.DecodeError(t, rec, http.StatusServiceUnavailable); got.Code != api.ErrCodeTimeout {
+		t.Errorf("got code %q, want %q", got.Code, api.ErrCodeTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("took %v to answer, want the timeout to cut the wait short", elapsed)
+	}
+}
+
+func TestCancelledRequestTouchesNothing(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := s.NewRequest(http.MethodPost, "/users", apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	rec := s.Serve(req.WithContext(ctx))
+
+	if got := apitest.DecodeError(t, rec, http.StatusServiceUnavailable); got.Code != api.ErrCodeTimeout {
+		t.Errorf("got code %q, want %q", got.Code, api.ErrCodeTimeout)
+	}
+	if users := s.ListUsers(""); len(users) != 0 {
+		t.Errorf("got %d users stored by a cancelled request, want none", len(users))
+	}
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"rocketseat/models"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Unique is implemented by models with a field that must not repeat across live records.
+type Unique interface {
+	UniqueField() string
+	UniqueKey() string
+}
+
+// CheckFields returns an error naming the first of fields that isn't a JSON field of T.
+func CheckFields[T any](fields []string) error {
+	known := jsonFieldNames(reflect.TypeFor[T]())
+	for _, field := range fields {
+		if !slices.Contains(known, field) {
+			return fmt.Errorf("unknown field %q", field)
+		}
+	}
+
+	return nil
+}
+
+// WithUnique makes fields unique across live records, on top of the model's own Unique key.
+// Values are compared ignoring case, like filters, unless uniqueCaseSensitive is set. It panics
+// on fields T doesn't have, since that's a mistake in the code setting up the routes.
+func (res *Resource[T]) WithUnique(fields ...string) *Resource[T] {
+	if err := CheckFields[T](fields); err != nil {
+		panic("api: WithUnique: " + err.Error())
+	}
+
+	for _, field := range fields {
+		if !slices.Contains(res.uniqueFields, field) {
+			res.uniqueFields = append(res.uniqueFields, field)
+		}
+	}
+
+	return res
+}
+
+// UniqueKeys returns the keys NewHandler keeps from repeating across live users under cfg, so
+// code writing users around the API, like seeding, can hold them to the same rule.
+func UniqueKeys(cfg Config) models.UniqueKeysFunc[*models.User] {
+	res := &Resource[*models.User]{uniqueCaseSensitive: cfg.UniqueCaseSensitive}
+	res.WithUnique(cfg.UniqueFields...)
+	return res.liveUniqueKeys
+}
+
+// uniqueKeys returns every key value must not share with another live record: the model's own
+// from Unique, then one per field passed to WithUnique. Unset fields have no key.
+func (res *Resource[T]) uniqueKeys(value T) []models.UniqueKey {
+	var keys []models.UniqueKey
+	if unique, ok := any(value).(Unique); ok && unique.UniqueKey() != "" {
+		keys = append(keys, models.UniqueKey{Field: unique.UniqueField(), Key: unique.UniqueKey()})
+	}
+
+	for _, field := range res.uniqueFields {
+		v, ok := fieldByJSONName(reflect.ValueOf(value), field)
+		for ok && v.Kind() == reflect.Pointer {
+			ok = !v.IsNil()
+			if ok {
+				v = v.Elem()
+			}
+		}
+		if !ok || v.IsZero() {
+			continue
+		}
+
+		key := fmt.Sprint(v.Interface())
+		if !res.uniqueCaseSensitive {
+			key = strings.ToLower(key)
+		}
+		keys = append(keys, models.UniqueKey{Field: field, Key: key})
+	}
+
+	return keys
+}
+
+// liveUniqueKeys is uniqueKeys for stores that enforce them, which know nothing of soft deletes:
+// a deleted record holds no keys, so a new one may take them.
+func (res *Resource[T]) liveUniqueKeys(value T) []models.UniqueKey {
+	if isDeleted(value) {
+		return nil
+	}
+
+	return res.uniqueKeys(value)
+}
+
+// checkUnique rejects value with a *models.ConflictError when another live record shares one of
+// its unique keys. id is the record being written, so updates and retried inserts don't
+// conflict with themselves; pass uuid.Nil for inserts without a client-chosen ID. Stores that are
+// a models.UniqueEnforcer check again as they write, which is what settles concurrent writes;
+// this check only answers early, and for backends that can't.
+func (res *Resource[T]) checkUnique(ctx context.Context, id uuid.UUID, value T) error {
+	keys := res.uniqueKeys(value)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	all, err := res.db.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for otherID, other := range all {
+		if otherID == id || isDeleted(other) {
+			continue
+		}
+
+		otherKeys := res.uniqueKeys(other)
+		for _, key := range keys {
+			if slices.Contains(otherKeys, key) {
+				return &models.ConflictError{Field: key.Field}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"rocketseat/models"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestInsertConflictsOnConfiguredUniqueField(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.UniqueFields = []string{"last_name"}
+	s := apitest.NewTestServerWithConfig(t, cfg)
+
+	s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+
+	rec := s.Do(http.MethodPost, "/users", apitest.NewUser("John", "Doe", "john@example.com"))
+	detail := apitest.DecodeError(t, rec, http.StatusConflict)
+	if !strings.Contains(detail.Message, "last_name") {
+		t.Errorf("got message %q, want it to name last_name", detail.Message)
+	}
+	if got := len(s.ListUsers("")); got != 1 {
+		t.Errorf("got %d users, want 1", got)
+	}
+}
+
+func TestUpdateConflictsOnConfiguredUniqueField(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.UniqueFields = []string{"last_name"}
+	s := apitest.NewTestServerWithConfig(t, cfg)
+
+	s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	john := s.InsertUser(apitest.NewUser("John", "Roe", "john@example.com"))
+
+	john.LastName = ptr("Doe")
+	rec := s.Do(http.MethodPut, "/users/"+john.ID.String(), john.User)
+	apitest.ExpectStatus(t, rec, http.StatusConflict)
+}
+
+// slowStore holds on to the snapshot the handler checks uniqueness against before handing it
+// over, widening the gap to the insert where concurrent requests could slip past each other.
+type slowStore struct {
+	*models.Store[*models.User]
+}
+
+func (s slowStore) GetAll(ctx context.Context) (map[uuid.UUID]*models.User, error) {
+	all, err := s.Store.GetAll(ctx)
+	time.Sleep(20 * time.Millisecond)
+	return all, err
+}
+
+func TestConcurrentInsertsOfOneEmailStoreOneUser(t *testing.T) {
+	store := models.NewStore[*models.User]()
+	cfg := api.DefaultConfig()
+	cfg.Logger = discardLogger()
+	handler := api.NewHandler(slowStore{store}, cfg)
+
+	const requests = 5
+	codes := make([]int, requests)
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := newJSONRequest(t, http.MethodPost, "/users", apitest.NewUser("Jane", "Doe", "jane@example.com"))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			codes[i] = rec.Code
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusConflict:
+		default:
+			t.Errorf("got status %d, want 201 or 409", code)
+		}
+	}
+	if created != 1 {
+		t.Errorf("got %d created, want 1; statuses %v", created, codes)
+	}
+
+	all, err := store.GetAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 {
+		t.Errorf("got %d stored users, want 1", len(all))
+	}
+}
+
+func TestUniqueFieldCase(t *testing.T) {
+	tests := []struct {
+		name          string
+		caseSensitive bool
+		want          int
+	}{
+		{"insensitive", false, http.StatusConflict},
+		{"sensitive", true, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := api.DefaultConfig()
+			cfg.UniqueFields = []string{"last_name"}
+			cfg.UniqueCaseSensitive = tt.caseSensitive
+			s := apitest.NewTestServerWithConfig(t, cfg)
+
+			s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+			rec := s.Do(http.MethodPost, "/users", apitest.NewUser("John", "DOE", "john@example.com"))
+			apitest.ExpectStatus(t, rec, tt.want)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package api_test
+
+import (
+	"net/http"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"rocketseat/models"
+	"slices"
+	"testing"
+)
+
+// errorFields returns the fields errs are about, in order.
+func errorFields(errs models.ValidationErrors) []string {
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	return fields
+}
+
+func TestEmailValidation(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+
+	rec := s.Do(http.MethodPost, "/users", apitest.NewUser("John", "Roe", "not-an-email"))
+	if got := errorFields(apitest.DecodeValidationErrors(t, rec)); !slices.Equal(got, []string{"email"}) {
+		t.Errorf("got errors on %v for a malformed email, want [email]", got)
+	}
+
+	rec = s.Do(http.MethodPost, "/users", apitest.NewUser("John", "Roe", "Jane@Example.com"))
+	if got := apitest.DecodeError(t, rec, http.StatusConflict); got.Code != api.ErrCodeConflict {
+		t.Errorf("got code %q for a duplicate email, want %q", got.Code, api.ErrCodeConflict)
+	}
+}
+
+func TestMissingFieldsAreEachReported(t *testing.T) {
+	s := apitest.NewTestServer(t)
+
+	user := apitest.NewUser("Jane", "Doe", "jane@example.com")
+	user.LastName = nil
+	user.Biography = nil
+	errs := apitest.DecodeValidationErrors(t, s.Do(http.MethodPost, "/users", user))
+
+	got := errorFields(errs)
+	slices.Sort(got)
+	if want := []string{"biography", "last_name"}; !slices.Equal(got, want) {
+		t.Errorf("got errors on %v, want %v", got, want)
+	}
+}
+
+func TestMaxLengthCountsRunes(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.MaxLengths = models.MaxLengths{"first_name": 5}
+	s := apitest.NewTestServerWithConfig(t, cfg)
+
+	// five runes but fifteen bytes
+	atLimit := apitest.NewUser("日本語のな", "Doe", "jane@example.com")
+	apitest.ExpectStatus(t, s.Do(http.MethodPost, "/users", atLimit), http.StatusCreated)
+
+	overLimit := apitest.NewUser("日本語のなま", "Roe", "john@example.com")
+	errs := apitest.DecodeValidationErrors(t, s.Do(http.MethodPost, "/users", overLimit))
+	if len(errs) != 1 || errs[0].Field != "first_name" || errs[0].Limit != 5 {
+		t.Errorf("got %+v, want one first_name error with limit 5", errs)
+	}
+}
@@ -0,0 +1,125 @@
+package api_test
+
+import (
+	"net/http"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"testing"
+	"time"
+)
+
+func TestSecondUpdateWithStaleVersionConflicts(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+
+	user.FirstName = ptr("Janet")
+	s.UpdateUser(user.ID, user.User)
+
+	// the same version again, as a client that missed the first update would send it
+	user.FirstName = ptr("Jenny")
+	rec := s.Do(http.MethodPut, "/users/"+user.ID.String(), user.User)
+	apitest.ExpectStatus(t, rec, http.StatusConflict)
+
+	if got := *s.GetUser(user.ID).FirstName; got != "Janet" {
+		t.Errorf("got first name %q, want the first update's Janet", got)
+	}
+}
+
+func TestUpdateAcceptsETagInIfMatch(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+
+	get := s.Do(http.MethodGet, "/users/"+user.ID.String(), nil)
+	etag := get.Header().Get("ETag")
+
+	user.Version = 0
+	user.FirstName = ptr("Janet")
+	req := s.NewRequest(http.MethodPut, "/users/"+user.ID.String(), user.User)
+	req.Header.Set("If-Match", etag)
+	apitest.ExpectStatus(t, s.Serve(req), http.StatusOK)
+
+	// the ETag went stale with the update
+	req = s.NewRequest(http.MethodPut, "/users/"+user.ID.String(), user.User)
+	req.Header.Set("If-Match", etag)
+	apitest.ExpectStatus(t, s.Serve(req), http.StatusPreconditionFailed)
+}
+
+func TestUpdateAcceptsVersionInIfMatch(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+
+	user.Version = 0
+	req := s.NewRequest(http.MethodPut, "/users/"+user.ID.String(), user.User)
+	req.Header.Set("If-Match", `"1"`)
+	apitest.ExpectStatus(t, s.Serve(req), http.StatusOK)
+}
+
+func TestDeleteChecksIfMatch(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	path := "/users/" + user.ID.String()
+
+	req := s.NewRequest(http.MethodDelete, path, nil)
+	req.Header.Set("If-Match", `"999"`)
+	apitest.ExpectStatus(t, s.Serve(req), http.StatusPreconditionFailed)
+
+	etag := s.Do(http.MethodGet, path, nil).Header().Get("ETag")
+	req = s.NewRequest(http.MethodDelete, path, nil)
+	req.Header.Set("If-Match", etag)
+	apitest.ExpectStatus(t, s.Serve(req), http.StatusNoContent)
+}
+
+func TestConditionalGet(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	path := "/users/" + user.ID.String()
+
+	first := s.Do(http.MethodGet, path, nil)
+	apitest.ExpectStatus(t, first, http.StatusOK)
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("got no ETag")
+	}
+
+	req := s.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("If-None-Match", etag)
+	rec := s.Serve(req)
+	apitest.ExpectStatus(t, rec, http.StatusNotModified)
+	if rec.Body.Len() != 0 {
+		t.Errorf("got a %d byte body with the 304, want none", rec.Body.Len())
+	}
+
+	// once the user changes, the old ETag no longer matches
+	user.FirstName = ptr("Janet")
+	s.UpdateUser(user.ID, user.User)
+	req = s.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("If-None-Match", etag)
+	apitest.ExpectStatus(t, s.Serve(req), http.StatusOK)
+}
+
+func TestIfUnmodifiedSince(t *testing.T) {
+	s := apitest.NewTestServer(t)
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	path := "/users/" + user.ID.String()
+	stale := user.UpdatedAt.Add(-time.Hour).UTC().Format(http.TimeFormat)
+	current := user.UpdatedAt.UTC().Format(http.TimeFormat)
+
+	user.FirstName = ptr("Janet")
+	req := s.NewRequest(http.MethodPut, path, user.User)
+	req.Header.Set("If-Unmodified-Since", stale)
+	if got := apitest.DecodeError(t, s.Serve(req), http.StatusPreconditionFailed); got.Code != api.ErrCodePreconditionFailed {
+		t.Errorf("got code %q, want %q", got.Code, api.ErrCodePreconditionFailed)
+	}
+	req = s.NewRequest(http.MethodDelete, path, nil)
+	req.Header.Set("If-Unmodified-Since", stale)
+	apitest.ExpectStatus(t, s.Serve(req), http.StatusPreconditionFailed)
+
+	req = s.NewRequest(http.MethodPut, path, user.User)
+	req.Header.Set("If-Unmodified-Since", current)
+	apitest.ExpectStatus(t, s.Serve(req), http.StatusOK)
+
+	updated := s.GetUser(user.ID)
+	req = s.NewRequest(http.MethodDelete, path, nil)
+	req.Header.Set("If-Unmodified-Since", updated.UpdatedAt.UTC().Format(http.TimeFormat))
+	apitest.ExpectStatus(t, s.Serve(req), http.StatusNoContent)
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultWebhookTimeout = 5 * time.Second
+	defaultWebhookBackoff = time.Second
+)
+
+type WebhookConfig struct {
+	// URL receives a POST for every change. Empty disables the webhook.
+	URL string
+	// Timeout bounds each delivery attempt. Zero means 5s.
+	Timeout time.Duration
+	// Retries is how many more times a failed delivery is attempted.
+	Retries int
+	// Backoff is the wait before the first retry, doubling for each one after. Zero means 1s.
+	Backoff time.Duration
+}
+
+// Webhook is an EventHook POSTing every change to a URL. Deliveries run in the background, so
+// a slow or failing receiver never holds up the request that made the change.
+type Webhook[T any] struct {
+	cfg     WebhookConfig
+	client  *http.Client
+	logger  *slog.Logger
+	pending sync.WaitGroup
+}
+
+func NewWebhook[T any](cfg WebhookConfig, logger *slog.Logger) *Webhook[T] {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultWebhookTimeout
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaultWebhookBackoff
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Webhook[T]{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}, logger: logger}
+}
+
+func (wh *Webhook[T]) OnCreate(ctx context.Context, record Response[T]) error {
+	return wh.send(ctx, EventCreate, record)
+}
+
+func (wh *Webhook[T]) OnUpdate(ctx context.Context, record Response[T]) error {
+	return wh.send(ctx, EventUpdate, record)
+}
+
+func (wh *Webhook[T]) OnDelete(ctx context.Context, record Response[T]) error {
+	return wh.send(ctx, EventDelete, record)
+}
+
+// Wait blocks until every delivery started so far has finished or given up, or ctx is done.
+func (wh *Webhook[T]) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		wh.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// send encodes the event now, while record still matches what was stored, and delivers it in
+// the background.
+func (wh *Webhook[T]) send(ctx context.Context, event string, record Response[T]) error {
+	body, err := json.Marshal(newEvent(event, record))
+	if err != nil {
+		return err
+	}
+
+	// the delivery outlives the request, but keeps its values for logging
+	ctx = context.WithoutCancel(ctx)
+	wh.pending.Add(1)
+	go func() {
+		defer wh.pending.Done()
+		wh.deliver(ctx, event, record.ID, body)
+	}()
+
+	return nil
+}
+
+func (wh *Webhook[T]) deliver(ctx context.Context, event string, id uuid.UUID, body []byte) {
+	backoff := wh.cfg.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := wh.post(ctx, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= wh.cfg.Retries {
+			wh.logger.ErrorContext(ctx, "webhook delivery failed", "event", event, "id", id, "attempts", attempt+1, "error", err)
+			return
+		}
+
+		wh.logger.WarnContext(ctx, "webhook delivery failed, retrying", "event", event, "id", id, "attempt", attempt+1, "backoff", backoff, "error", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post makes one delivery attempt. retry reports whether a failure may pass on a later attempt:
+// a client error other than 429 would only be answered the same way again.
+func (wh *Webhook[T]) post(ctx context.Context, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentTypeJSON)
+
+	resp, err := wh.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("webhook answered %s", resp.Status)
+}
@@ -0,0 +1,74 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"rocketseat/api"
+	"rocketseat/api/apitest"
+	"rocketseat/models"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhookPostsCreate(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- r
+		bodies <- body
+	}))
+	defer receiver.Close()
+
+	webhook := api.NewWebhook[*models.User](api.WebhookConfig{URL: receiver.URL}, discardLogger())
+	cfg := api.DefaultConfig()
+	cfg.EventHook = webhook
+	s := apitest.NewTestServerWithConfig(t, cfg)
+
+	user := s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	if err := webhook.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-requests
+	if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("got %s with Content-Type %q, want a JSON POST", req.Method, req.Header.Get("Content-Type"))
+	}
+	var event api.Event[*models.User]
+	if err := json.Unmarshal(<-bodies, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != api.EventCreate || event.ID != user.ID {
+		t.Errorf("got %s event for %s, want create for %s", event.Type, event.ID, user.ID)
+	}
+	if event.Payload == nil || *event.Payload.Email != "jane@example.com" {
+		t.Errorf("got payload %+v, want the created user", event.Payload)
+	}
+}
+
+func TestWebhookRetriesServerErrors(t *testing.T) {
+	var attempts atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	webhook := api.NewWebhook[*models.User](api.WebhookConfig{URL: receiver.URL, Retries: 2, Backoff: time.Millisecond}, discardLogger())
+	cfg := api.DefaultConfig()
+	cfg.EventHook = webhook
+	s := apitest.NewTestServerWithConfig(t, cfg)
+
+	s.InsertUser(apitest.NewUser("Jane", "Doe", "jane@example.com"))
+	if err := webhook.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := attempts.Load(); n != 3 {
+		t.Errorf("got %d attempts, want 3", n)
+	}
+}
@@ -0,0 +1,462 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"rocketseat/api"
+	"rocketseat/models"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type config struct {
+	Addr         string
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	Backend      string
+	// RecordTTL is how long records of the memory backend live, zero meaning until deleted.
+	RecordTTL time.Duration
+	// MaxRecords caps how many users the memory and file backends hold, zero meaning no cap.
+	MaxRecords int
+	// IndexedFields are user fields the memory and file backends index, so listings filtering
+	// on them don't scan every user.
+	IndexedFields []string
+	// SweepInterval is how often the memory backend drops expired records it hasn't read since.
+	SweepInterval time.Duration
+	DataFile      string
+	SQLiteDSN     string
+	LogLevel      slog.Level
+	// LogFormat is "text" or "json".
+	LogFormat string
+	// Maintenance starts the API in maintenance mode. SIGUSR1 and the admin endpoint switch it.
+	Maintenance bool
+	// MaintenanceRetryAfter is the Retry-After sent with the 503s of maintenance mode.
+	MaintenanceRetryAfter time.Duration
+	// AuditLog is where the audit log goes: "log" for the application log, or the path of a
+	// file to append JSON lines to. Empty disables it.
+	AuditLog string
+	// Webhook is where user changes are POSTed, if anywhere.
+	Webhook api.WebhookConfig
+	API     api.Config
+}
+
+func defaultConfig() config {
+	return config{
+		Addr:                  "localhost:8080",
+		ReadTimeout:           time.Second * 10,
+		WriteTimeout:          time.Second * 10,
+		IdleTimeout:           time.Minute,
+		Backend:               "file",
+		SweepInterval:         time.Minute,
+		DataFile:              "./data.json",
+		SQLiteDSN:             "./data.db",
+		LogLevel:              slog.LevelInfo,
+		LogFormat:             "text",
+		Webhook:               api.WebhookConfig{Retries: 3},
+		MaintenanceRetryAfter: time.Minute,
+		API:                   api.DefaultConfig(),
+	}
+}
+
+// loadConfig starts from the defaults, overrides them with the config file at path, if any, and
+// then with whatever is set in the environment.
+func loadConfig(path string) (config, error) {
+	cfg := defaultConfig()
+
+	src, err := loadSettings(path)
+	if err != nil {
+		return config{}, err
+	}
+
+	if addr, ok := src.lookup("ADDR"); ok {
+		cfg.Addr = addr
+	}
+
+	if backend, ok := src.lookup("STORE_BACKEND"); ok {
+		cfg.Backend = backend
+	}
+
+	if raw, ok := src.lookup("MAX_RECORDS"); ok {
+		maxRecords, err := strconv.Atoi(raw)
+		if err != nil || maxRecords < 0 {
+			return config{}, fmt.Errorf("invalid MAX_RECORDS %q: must be a non-negative number", raw)
+		}
+		cfg.MaxRecords = maxRecords
+	}
+
+	if raw, ok := src.lookup("INDEXED_FIELDS"); ok {
+		fields := splitList(raw)
+		if err := api.CheckFields[*models.User](fields); err != nil {
+			return config{}, fmt.Errorf("invalid INDEXED_FIELDS %q: %w", raw, err)
+		}
+		cfg.IndexedFields = fields
+	}
+
+	if raw, ok := src.lookup("LOG_LEVEL"); ok {
+		if err := cfg.LogLevel.UnmarshalText([]byte(raw)); err != nil {
+			return config{}, fmt.Errorf("invalid LOG_LEVEL %q: expected debug, info, warn or error", raw)
+		}
+	}
+
+	if raw, ok := src.lookup("LOG_FORMAT"); ok {
+		if raw != "text" && raw != "json" {
+			return config{}, fmt.Errorf("invalid LOG_FORMAT %q: expected text or json", raw)
+		}
+		cfg.LogFormat = raw
+	}
+
+	if raw, ok := src.lookup("TRAILING_SLASH"); ok {
+		switch raw {
+		case api.TrailingSlashStrip, api.TrailingSlashRedirect, api.TrailingSlashStrict:
+			cfg.API.TrailingSlash = raw
+		default:
+			return config{}, fmt.Errorf("invalid TRAILING_SLASH %q: expected strip, redirect or strict", raw)
+		}
+	}
+
+	if basePath, ok := src.lookup("BASE_PATH"); ok {
+		cfg.API.BasePath = basePath
+	}
+
+	if dataFile, ok := src.lookup("DATA_FILE"); ok {
+		cfg.DataFile = dataFile
+	}
+
+	if dsn, ok := src.lookup("SQLITE_DSN"); ok {
+		cfg.SQLiteDSN = dsn
+	}
+
+	if secret, ok := src.lookup("JWT_SECRET"); ok {
+		cfg.API.UserAuth.Secret = []byte(secret)
+	}
+
+	if raw, ok := src.lookup("AUTH_PROTECT_READS"); ok {
+		protectReads, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid AUTH_PROTECT_READS %q: %w", raw, err)
+		}
+		cfg.API.UserAuth.ProtectReads = protectReads
+	}
+
+	if raw, ok := src.lookup("API_KEYS"); ok {
+		cfg.API.APIKey.Keys = splitList(raw)
+	}
+
+	if header, ok := src.lookup("API_KEY_HEADER"); ok {
+		cfg.API.APIKey.Header = header
+	}
+
+	if raw, ok := src.lookup("ID_VERSION"); ok {
+		switch raw {
+		case "v4":
+			cfg.API.IDGenerator = api.UUIDv4{}
+		case "v7":
+			cfg.API.IDGenerator = api.UUIDv7{}
+		default:
+			return config{}, fmt.Errorf("invalid ID_VERSION %q: expected v4 or v7", raw)
+		}
+	}
+
+	if raw, ok := src.lookup("ID_FORMAT"); ok {
+		switch raw {
+		case api.IDFormatCanonical, api.IDFormatNoHyphens, api.IDFormatURN:
+			cfg.API.IDFormat = raw
+		default:
+			return config{}, fmt.Errorf("invalid ID_FORMAT %q: expected canonical, no-hyphens or urn", raw)
+		}
+	}
+
+	if raw, ok := src.lookup("LOG_PANIC_STACKS"); ok {
+		logPanicStacks, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid LOG_PANIC_STACKS %q: %w", raw, err)
+		}
+		cfg.API.LogPanicStacks = logPanicStacks
+	}
+
+	if raw, ok := src.lookup("STRICT_QUERY"); ok {
+		strictQuery, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid STRICT_QUERY %q: %w", raw, err)
+		}
+		cfg.API.StrictQuery = strictQuery
+	}
+
+	if raw, ok := src.lookup("DEFAULT_SORT"); ok {
+		if err := api.CheckSort[*models.User](raw); err != nil {
+			return config{}, fmt.Errorf("invalid DEFAULT_SORT %q: %w", raw, err)
+		}
+		cfg.API.DefaultSort = raw
+	}
+
+	if raw, ok := src.lookup("MAX_LIMIT"); ok {
+		maxLimit, err := strconv.Atoi(raw)
+		if err != nil || maxLimit < 0 {
+			return config{}, fmt.Errorf("invalid MAX_LIMIT %q: must be a non-negative number", raw)
+		}
+		cfg.API.MaxLimit = maxLimit
+	}
+
+	if raw, ok := src.lookup("REJECT_OVER_LIMIT"); ok {
+		reject, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid REJECT_OVER_LIMIT %q: %w", raw, err)
+		}
+		cfg.API.RejectOverLimit = reject
+	}
+
+	if raw, ok := src.lookup("PUT_CREATES"); ok {
+		putCreates, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid PUT_CREATES %q: %w", raw, err)
+		}
+		cfg.API.PutCreates = putCreates
+	}
+
+	if raw, ok := src.lookup("IDEMPOTENT_DELETE"); ok {
+		idempotentDelete, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid IDEMPOTENT_DELETE %q: %w", raw, err)
+		}
+		cfg.API.IdempotentDelete = idempotentDelete
+	}
+
+	if raw, ok := src.lookup("BARE_LISTS"); ok {
+		bareLists, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid BARE_LISTS %q: %w", raw, err)
+		}
+		cfg.API.BareLists = bareLists
+	}
+
+	if raw, ok := src.lookup("MAX_LENGTHS"); ok {
+		maxLengths := models.MaxLengths{}
+		for _, item := range splitList(raw) {
+			field, rawLimit, _ := strings.Cut(item, "=")
+			limit, err := strconv.Atoi(rawLimit)
+			if err != nil || limit < 1 {
+				return config{}, fmt.Errorf("invalid MAX_LENGTHS %q: %q must be field=N with N positive", raw, item)
+			}
+			maxLengths[strings.TrimSpace(field)] = limit
+		}
+		if err := api.CheckFields[*models.User](slices.Collect(maps.Keys(maxLengths))); err != nil {
+			return config{}, fmt.Errorf("invalid MAX_LENGTHS %q: %w", raw, err)
+		}
+		cfg.API.MaxLengths = maxLengths
+	}
+
+	if raw, ok := src.lookup("UNIQUE_FIELDS"); ok {
+		fields := splitList(raw)
+		if err := api.CheckFields[*models.User](fields); err != nil {
+			return config{}, fmt.Errorf("invalid UNIQUE_FIELDS %q: %w", raw, err)
+		}
+		cfg.API.UniqueFields = fields
+	}
+
+	if raw, ok := src.lookup("UNIQUE_CASE_SENSITIVE"); ok {
+		caseSensitive, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid UNIQUE_CASE_SENSITIVE %q: %w", raw, err)
+		}
+		cfg.API.UniqueCaseSensitive = caseSensitive
+	}
+
+	if raw, ok := src.lookup("ALLOW_ADMIN"); ok {
+		allowAdmin, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid ALLOW_ADMIN %q: %w", raw, err)
+		}
+		cfg.API.AllowAdmin = allowAdmin
+	}
+
+	if raw, ok := src.lookup("DEBUG_VARS"); ok {
+		debugVars, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid DEBUG_VARS %q: %w", raw, err)
+		}
+		cfg.API.DebugVars = debugVars
+	}
+
+	if raw, ok := src.lookup("PPROF"); ok {
+		pprof, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid PPROF %q: %w", raw, err)
+		}
+		cfg.API.Pprof = pprof
+	}
+
+	if auditLog, ok := src.lookup("AUDIT_LOG"); ok {
+		cfg.AuditLog = auditLog
+	}
+
+	if raw, ok := src.lookup("AUDIT_DIFF"); ok {
+		diff, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid AUDIT_DIFF %q: %w", raw, err)
+		}
+		cfg.API.Audit.Diff = diff
+	}
+
+	if raw, ok := src.lookup("AUDIT_REDACT"); ok {
+		fields := splitList(raw)
+		if err := api.CheckFields[*models.User](fields); err != nil {
+			return config{}, fmt.Errorf("invalid AUDIT_REDACT %q: %w", raw, err)
+		}
+		cfg.API.Audit.Redact = fields
+	}
+
+	if raw, ok := src.lookup("MAINTENANCE"); ok {
+		maintenance, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid MAINTENANCE %q: %w", raw, err)
+		}
+		cfg.Maintenance = maintenance
+	}
+
+	if raw, ok := src.lookup("ALLOW_CLEAR"); ok {
+		allowClear, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid ALLOW_CLEAR %q: %w", raw, err)
+		}
+		cfg.API.AllowClear = allowClear
+	}
+
+	if raw, ok := src.lookup("RATE_LIMIT_RPS"); ok {
+		rps, err := strconv.ParseFloat(raw, 64)
+		if err != nil || rps < 0 {
+			return config{}, fmt.Errorf("invalid RATE_LIMIT_RPS %q: must be a non-negative number", raw)
+		}
+		cfg.API.RateLimit.RequestsPerSecond = rps
+	}
+
+	if raw, ok := src.lookup("RATE_LIMIT_BURST"); ok {
+		burst, err := strconv.Atoi(raw)
+		if err != nil || burst < 1 {
+			return config{}, fmt.Errorf("invalid RATE_LIMIT_BURST %q: must be a positive number", raw)
+		}
+		cfg.API.RateLimit.Burst = burst
+	}
+
+	if raw, ok := src.lookup("TRUST_FORWARDED_FOR"); ok {
+		trust, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid TRUST_FORWARDED_FOR %q: %w", raw, err)
+		}
+		cfg.API.RateLimit.TrustForwardedFor = trust
+	}
+
+	if raw, ok := src.lookup("CORS_ALLOW_CREDENTIALS"); ok {
+		allowCredentials, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid CORS_ALLOW_CREDENTIALS %q: %w", raw, err)
+		}
+		cfg.API.CORS.AllowCredentials = allowCredentials
+	}
+
+	lists := []struct {
+		key    string
+		target *[]string
+	}{
+		{"CORS_ALLOWED_ORIGINS", &cfg.API.CORS.AllowedOrigins},
+		{"CORS_ALLOWED_METHODS", &cfg.API.CORS.AllowedMethods},
+		{"CORS_ALLOWED_HEADERS", &cfg.API.CORS.AllowedHeaders},
+	}
+
+	for _, l := range lists {
+		if raw, ok := src.lookup(l.key); ok {
+			*l.target = splitList(raw)
+		}
+	}
+
+	if raw, ok := src.lookup("MAX_BODY_BYTES"); ok {
+		maxBodyBytes, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || maxBodyBytes <= 0 {
+			return config{}, fmt.Errorf("invalid MAX_BODY_BYTES %q: must be a positive number of bytes", raw)
+		}
+		cfg.API.MaxBodyBytes = maxBodyBytes
+	}
+
+	if raw, ok := src.lookup("STRICT_CONTENT_LENGTH"); ok {
+		strict, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid STRICT_CONTENT_LENGTH %q: %w", raw, err)
+		}
+		cfg.API.StrictContentLength = strict
+	}
+
+	if url, ok := src.lookup("WEBHOOK_URL"); ok {
+		cfg.Webhook.URL = url
+	}
+
+	if raw, ok := src.lookup("WEBHOOK_RETRIES"); ok {
+		retries, err := strconv.Atoi(raw)
+		if err != nil || retries < 0 {
+			return config{}, fmt.Errorf("invalid WEBHOOK_RETRIES %q: must be a non-negative number", raw)
+		}
+		cfg.Webhook.Retries = retries
+	}
+
+	if raw, ok := src.lookup("COMPRESS_MIN_BYTES"); ok {
+		minBytes, err := strconv.Atoi(raw)
+		if err != nil || minBytes < 0 {
+			return config{}, fmt.Errorf("invalid COMPRESS_MIN_BYTES %q: must be a non-negative number of bytes", raw)
+		}
+		cfg.API.CompressMinBytes = minBytes
+	}
+
+	durations := []struct {
+		key    string
+		target *time.Duration
+	}{
+		{"READ_TIMEOUT", &cfg.ReadTimeout},
+		{"WRITE_TIMEOUT", &cfg.WriteTimeout},
+		{"IDLE_TIMEOUT", &cfg.IdleTimeout},
+		{"REQUEST_TIMEOUT", &cfg.API.RequestTimeout},
+		{"SLOW_REQUEST_THRESHOLD", &cfg.API.SlowRequestThreshold},
+		{"RECORD_TTL", &cfg.RecordTTL},
+		{"SWEEP_INTERVAL", &cfg.SweepInterval},
+		{"WEBHOOK_TIMEOUT", &cfg.Webhook.Timeout},
+		{"WEBHOOK_BACKOFF", &cfg.Webhook.Backoff},
+		{"CORS_MAX_AGE", &cfg.API.CORS.MaxAge},
+		{"MAINTENANCE_RETRY_AFTER", &cfg.MaintenanceRetryAfter},
+	}
+
+	for _, d := range durations {
+		raw, ok := src.lookup(d.key)
+		if !ok {
+			continue
+		}
+
+		value, err := time.ParseDuration(raw)
+		if err != nil {
+			return config{}, fmt.Errorf("invalid %s %q: %w", d.key, raw, err)
+		}
+		*d.target = value
+	}
+
+	if err := cfg.API.CORS.Validate(); err != nil {
+		return config{}, fmt.Errorf("invalid CORS settings: %w", err)
+	}
+
+	if unknown := src.unknown(); len(unknown) > 0 {
+		return config{}, fmt.Errorf("unknown keys in config file %s: %s", path, strings.Join(unknown, ", "))
+	}
+
+	return cfg, nil
+}
+
+// splitList parses a comma-separated env value, dropping blanks around and between items.
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerSettingsFromEnvironment(t *testing.T) {
+	t.Setenv("ADDR", ":9090")
+	t.Setenv("READ_TIMEOUT", "3s")
+	t.Setenv("WRITE_TIMEOUT", "4s")
+	t.Setenv("IDLE_TIMEOUT", "2m")
+
+	cfg, err := loadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newServer(cfg, http.NotFoundHandler())
+
+	if s.Addr != ":9090" {
+		t.Errorf("got Addr %q, want :9090", s.Addr)
+	}
+	if s.ReadTimeout != 3*time.Second || s.WriteTimeout != 4*time.Second || s.IdleTimeout != 2*time.Minute {
+		t.Errorf("got timeouts %v, %v, %v, want 3s, 4s, 2m", s.ReadTimeout, s.WriteTimeout, s.IdleTimeout)
+	}
+}
+
+func TestServerSettingsDefault(t *testing.T) {
+	cfg, err := loadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newServer(cfg, http.NotFoundHandler())
+
+	if s.Addr != "localhost:8080" || s.ReadTimeout != 10*time.Second {
+		t.Errorf("got Addr %q and ReadTimeout %v, want the defaults", s.Addr, s.ReadTimeout)
+	}
+}
+
+func TestInvalidTimeoutIsAnError(t *testing.T) {
+	t.Setenv("READ_TIMEOUT", "soon")
+
+	_, err := loadConfig("")
+	if err == nil || !strings.Contains(err.Error(), "READ_TIMEOUT") {
+		t.Errorf("got error %v, want one naming READ_TIMEOUT", err)
+	}
+}
+
+func TestLogLevelFromEnvironment(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("LOG_FORMAT", "json")
+
+	cfg, err := loadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	logger := newLogger(cfg, &buf)
+
+	logger.Info("suppressed")
+	logger.Warn("kept")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want only the warning: %s", len(lines), buf.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["msg"] != "kept" || entry["level"] != "WARN" {
+		t.Errorf("got %v, want the warning", entry)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// settings looks up config values, taking the environment over the config file. File keys are
+// the environment variable names in lowercase, like read_timeout for READ_TIMEOUT, so both are
+// parsed and validated by the same code.
+type settings struct {
+	file map[string]string
+	// read collects every key asked for, so file keys nothing reads can be reported as unknown.
+	read map[string]bool
+}
+
+// loadSettings reads the YAML or JSON file at path, which may be empty for no file. Since JSON
+// is a subset of YAML, both go through the same decoder.
+func loadSettings(path string) (*settings, error) {
+	s := &settings{file: map[string]string{}, read: map[string]bool{}}
+	if path == "" {
+		return s, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var values map[string]any
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return nil, fmt.Errorf("config file %s is malformed: %w", path, err)
+	}
+
+	for key, value := range values {
+		s.file[strings.ToUpper(key)] = settingString(value)
+	}
+
+	return s, nil
+}
+
+// settingString flattens a file value into the form its environment variable takes: lists are
+// comma-separated and maps, like max_lengths, become key=value pairs.
+func settingString(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case []any:
+		items := make([]string, len(value))
+		for i, item := range value {
+			items[i] = settingString(item)
+		}
+		return strings.Join(items, ",")
+	case map[string]any:
+		pairs := make([]string, 0, len(value))
+		for key, item := range value {
+			pairs = append(pairs, key+"="+settingString(item))
+		}
+		slices.Sort(pairs)
+		return strings.Join(pairs, ",")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// lookup returns the value of key from the environment, or failing that from the file.
+func (s *settings) lookup(key string) (string, bool) {
+	s.read[key] = true
+	if raw, ok := os.LookupEnv(key); ok {
+		return raw, true
+	}
+
+	raw, ok := s.file[key]
+	return raw, ok
+}
+
+// unknown returns the file keys no setting was read from, in lowercase and sorted.
+func (s *settings) unknown() []string {
+	var keys []string
+	for key := range s.file {
+		if !s.read[key] {
+			keys = append(keys, strings.ToLower(key))
+		}
+	}
+	slices.Sort(keys)
+
+	return keys
+}
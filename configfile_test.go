@@ -0,0 +1,68 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+addr: ":9000"
+read_timeout: 3s
+log_level: debug
+allow_admin: true
+unique_fields: [last_name]
+max_lengths:
+  biography: 2000
+`)
+	t.Setenv("ADDR", ":9999")
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":9999" {
+		t.Errorf("got Addr %q, want the environment's :9999", cfg.Addr)
+	}
+	if cfg.ReadTimeout != 3*time.Second || cfg.LogLevel != slog.LevelDebug {
+		t.Errorf("got ReadTimeout %v and LogLevel %v, want 3s and debug", cfg.ReadTimeout, cfg.LogLevel)
+	}
+	if !cfg.API.AllowAdmin || !slices.Equal(cfg.API.UniqueFields, []string{"last_name"}) || cfg.API.MaxLengths["biography"] != 2000 {
+		t.Errorf("got AllowAdmin %v, UniqueFields %v and MaxLengths %v, want the file's", cfg.API.AllowAdmin, cfg.API.UniqueFields, cfg.API.MaxLengths)
+	}
+}
+
+func TestLoadJSONConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "config.json", `{"addr": ":9000", "write_timeout": "4s"}`)
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":9000" || cfg.WriteTimeout != 4*time.Second {
+		t.Errorf("got Addr %q and WriteTimeout %v, want :9000 and 4s", cfg.Addr, cfg.WriteTimeout)
+	}
+}
+
+func TestConfigFileUnknownKeys(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "addr: \":9000\"\nadress: \":9001\"\n")
+
+	_, err := loadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "adress") {
+		t.Errorf("got %v, want an error naming adress", err)
+	}
+}
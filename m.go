@@ -1,13 +1,24 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"rocketseat/api"
 	"rocketseat/models"
+	"syscall"
 	"time"
 )
 
+const shutdownTimeout = time.Second * 10
+
 func main() {
 	if err := run(); err != nil {
 		slog.Error("failed to execute code", "error", err)
@@ -16,21 +27,173 @@ func main() {
 	slog.Info("all systems offline")
 }
 
-func run() error {
-	db := models.DB[*models.User]{}
-	handler := api.NewHandler(db)
-
-	s := http.Server{
-		ReadTimeout:  time.Second * 10,
-		IdleTimeout:  time.Minute,
-		WriteTimeout: time.Second * 10,
-		Addr:         "localhost:8080",
+// newStorage picks the backend named by cfg.Backend: "memory", "file" or "sqlite".
+func newStorage(cfg config) (models.Storage[*models.User], error) {
+	switch cfg.Backend {
+	case "memory":
+		store := models.NewTTLStore[*models.User](cfg.RecordTTL)
+		store.SetMaxRecords(cfg.MaxRecords)
+		addIndexes(store.Store, cfg.IndexedFields)
+		return store, nil
+	case "file":
+		store, err := models.NewFileStore[*models.User](cfg.DataFile)
+		if err != nil {
+			return nil, err
+		}
+		store.SetMaxRecords(cfg.MaxRecords)
+		addIndexes(store, cfg.IndexedFields)
+		return store, nil
+	case "sqlite":
+		if cfg.MaxRecords > 0 {
+			return nil, errors.New("MAX_RECORDS isn't supported by the sqlite backend")
+		}
+		if len(cfg.IndexedFields) > 0 {
+			return nil, errors.New("INDEXED_FIELDS isn't supported by the sqlite backend")
+		}
+		if len(cfg.API.UniqueFields) > 0 {
+			return nil, errors.New("UNIQUE_FIELDS isn't supported by the sqlite backend")
+		}
+		return newSQLiteStorage(cfg.SQLiteDSN)
+	default:
+		return nil, fmt.Errorf("unknown STORE_BACKEND %q, expected memory, file or sqlite", cfg.Backend)
+	}
+}
+
+// addIndexes indexes store by each of fields the way listings filter on them.
+func addIndexes(store *models.Store[*models.User], fields []string) {
+	for _, field := range fields {
+		store.AddIndex(field, api.FieldIndex[*models.User](field))
+	}
+}
+
+// newLogger builds the logger described by cfg, writing to w and tagging lines with the request
+// ID when logged with a request's context.
+func newLogger(cfg config, w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.LogLevel}
+
+	var handler slog.Handler
+	if cfg.LogFormat == "json" {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+
+	return slog.New(api.ContextHandler{Handler: handler})
+}
+
+func newServer(cfg config, handler http.Handler) *http.Server {
+	return &http.Server{
+		ReadTimeout:  cfg.ReadTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		Addr:         cfg.Addr,
 		Handler:      handler,
 	}
+}
+
+func run() error {
+	seedFile := flag.String("seed", "", "CSV file of users to load before serving")
+	configFile := flag.String("config", "", "YAML or JSON file of settings, which the environment overrides")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	logger := newLogger(cfg, os.Stderr)
+	slog.SetDefault(logger)
+	cfg.API.Logger = logger
+
+	db, err := newStorage(cfg)
+	if err != nil {
+		return err
+	}
+	if closer, ok := db.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if ttlStore, ok := db.(*models.TTLStore[*models.User]); ok && cfg.SweepInterval > 0 {
+		go ttlStore.RunSweeper(ctx, cfg.SweepInterval)
+	}
+
+	if *seedFile != "" {
+		count, err := seedUsers(ctx, db, *seedFile, cfg.API)
+		if err != nil {
+			return err
+		}
+		slog.Info("seeded users", "file", *seedFile, "count", count)
+	}
+
+	switch cfg.AuditLog {
+	case "":
+	case "log":
+		cfg.API.Audit.Logger = logger
+	default:
+		file, err := os.OpenFile(cfg.AuditLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return fmt.Errorf("opening audit log: %w", err)
+		}
+		defer file.Close()
+		cfg.API.Audit.Logger = slog.New(slog.NewJSONHandler(file, nil))
+	}
+
+	var webhook *api.Webhook[*models.User]
+	if cfg.Webhook.URL != "" {
+		webhook = api.NewWebhook[*models.User](cfg.Webhook, logger)
+		cfg.API.EventHook = webhook
+	}
 
-	if err := s.ListenAndServe(); err != nil {
+	maintenance := api.NewMaintenance(cfg.MaintenanceRetryAfter)
+	maintenance.Set(cfg.Maintenance)
+	cfg.API.Maintenance = maintenance
+	go toggleMaintenanceOnSignal(ctx, maintenance)
+
+	handler := api.NewHandler(db, cfg.API)
+
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
 		return err
 	}
+	slog.Info("listening", "addr", cfg.Addr, "trailing_slash", cfg.API.TrailingSlash)
+
+	if err := serve(ctx, newServer(cfg, handler), listener); err != nil {
+		return err
+	}
+
+	if webhook != nil {
+		waitCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := webhook.Wait(waitCtx); err != nil {
+			slog.Warn("gave up on pending webhook deliveries", "error", err)
+		}
+	}
 
 	return nil
 }
+
+// serve answers requests on listener until ctx is done, then stops taking new ones and gives
+// those in flight up to shutdownTimeout to finish.
+func serve(ctx context.Context, s *http.Server, listener net.Listener) error {
+	serverErr := make(chan error, 1)
+	go func() {
+		if err := s.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+		slog.Info("shutting down, draining open connections", "timeout", shutdownTimeout)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	return s.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShutdownLetsRequestsInFlightFinish(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- serve(ctx, &http.Server{Handler: handler}, listener) }()
+
+	url := "http://" + listener.Addr().String()
+	type result struct {
+		body string
+		err  error
+	}
+	answered := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			answered <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		answered <- result{string(body), err}
+	}()
+
+	<-started
+	stop()
+
+	// shutdown waits on the request, so serve can't return until it's released
+	select {
+	case err := <-served:
+		t.Fatalf("serve returned %v with a request in flight", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+
+	if got := <-answered; got.err != nil || got.body != "done" {
+		t.Errorf("got %q, %v, want the request to complete", got.body, got.err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("serve returned %v, want nil", err)
+	}
+	if _, err := http.Get(url); err == nil {
+		t.Error("got an answer after shutdown, want the connection refused")
+	}
+}
+
+func TestSQLiteRefusesUniqueFields(t *testing.T) {
+	cfg, err := loadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Backend = "sqlite"
+	cfg.API.UniqueFields = []string{"nickname"}
+
+	// only email is unique in the sqlite schema, so other fields would go unchecked on write
+	if _, err := newStorage(cfg); err == nil || !strings.Contains(err.Error(), "UNIQUE_FIELDS") {
+		t.Errorf("got %v, want UNIQUE_FIELDS refused", err)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package main
+
+import (
+	"context"
+	"rocketseat/api"
+)
+
+// toggleMaintenanceOnSignal does nothing where there's no SIGUSR1; /admin/maintenance still
+// switches maintenance mode.
+func toggleMaintenanceOnSignal(ctx context.Context, maintenance *api.Maintenance) {}
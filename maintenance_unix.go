@@ -0,0 +1,28 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"rocketseat/api"
+	"syscall"
+)
+
+// toggleMaintenanceOnSignal flips maintenance mode on every SIGUSR1 until ctx is done.
+func toggleMaintenanceOnSignal(ctx context.Context, maintenance *api.Maintenance) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-signals:
+			slog.Info("maintenance mode switched", "enabled", maintenance.Toggle())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
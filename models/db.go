@@ -1,5 +1,292 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
 
 type DB[T any] map[uuid.UUID]T
+
+// Store wraps a DB with a RWMutex so handlers can share it across goroutines.
+// When path is set, the whole DB is written back to that JSON file after every mutation.
+// Nothing it does blocks for long, so contexts are only checked before starting: a request
+// already cancelled or past its deadline gets the context's error instead.
+type Store[T any] struct {
+	mu   sync.RWMutex
+	data DB[T]
+	path string
+	// maxRecords caps how many records Insert lets the store hold, zero meaning no cap.
+	maxRecords int
+	// indexes are the secondary indexes AddIndex set up, by field.
+	indexes map[string]*index[T]
+	// uniqueKeys, when set, gives the keys Insert and Update keep from repeating, and unique
+	// indexes the records by them.
+	uniqueKeys UniqueKeysFunc[T]
+	unique     *index[T]
+}
+
+func NewStore[T any]() *Store[T] {
+	return &Store[T]{data: DB[T]{}}
+}
+
+// NewFileStore loads the store from the JSON file at path, starting empty if it doesn't exist yet.
+// A file that exists but can't be parsed is an error, so a bad file is never silently overwritten.
+func NewFileStore[T any](path string) (*Store[T], error) {
+	s := &Store[T]{data: DB[T]{}, path: path}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading data file %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(content, &s.data); err != nil {
+		return nil, fmt.Errorf("data file %s is corrupt: %w", path, err)
+	}
+	if s.data == nil {
+		s.data = DB[T]{}
+	}
+
+	return s, nil
+}
+
+// SetMaxRecords makes Insert fail with a FullError once the store holds max records, soft-deleted
+// ones included, so a store kept in memory can't grow without bound. Zero removes the cap.
+func (s *Store[T]) SetMaxRecords(max int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.maxRecords = max
+}
+
+func (s *Store[T]) MaxRecords() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.maxRecords
+}
+
+// persist writes the DB to a temporary file and renames it over path, so a crash mid-write
+// leaves the previous version intact. Callers must hold the write lock.
+func (s *Store[T]) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding data file %s: %w", s.path, err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o644); err != nil {
+		return fmt.Errorf("writing data file %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("replacing data file %s: %w", s.path, err)
+	}
+
+	return nil
+}
+
+// Ping checks the directory holding the data file is still there; a purely in-memory store is always ready.
+func (s *Store[T]) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if s.path == "" {
+		return nil
+	}
+
+	if _, err := os.Stat(filepath.Dir(s.path)); err != nil {
+		return fmt.Errorf("data file directory unavailable: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store[T]) Get(ctx context.Context, id uuid.UUID) (T, error) {
+	if err := ctx.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, ok := s.data[id]
+	if !ok {
+		return value, ErrNotFound
+	}
+
+	return value, nil
+}
+
+func (s *Store[T]) GetAll(ctx context.Context) (map[uuid.UUID]T, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[uuid.UUID]T, len(s.data))
+	for key, value := range s.data {
+		result[key] = value
+	}
+
+	return result, nil
+}
+
+// List returns deep copies of every record, sorted by ID. Copies go through JSON, the same
+// encoding the data file uses, so nothing the caller holds aliases what the store keeps.
+func (s *Store[T]) List(ctx context.Context) ([]Entry[T], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]Entry[T], 0, len(s.data))
+	for id, value := range s.data {
+		copied, err := deepCopy(value)
+		if err != nil {
+			return nil, fmt.Errorf("copying record %s: %w", id, err)
+		}
+		entries = append(entries, Entry[T]{ID: id, Value: copied})
+	}
+
+	slices.SortFunc(entries, func(a, b Entry[T]) int {
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+
+	return entries, nil
+}
+
+func deepCopy[T any](value T) (T, error) {
+	var copied T
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return copied, err
+	}
+	err = json.Unmarshal(encoded, &copied)
+
+	return copied, err
+}
+
+// Insert stores value under id, returning a *ConflictError on id if the id is taken, or on the
+// field of a unique key another record has. If the data file can't be written the insert is
+// undone, so memory never holds records the file doesn't.
+func (s *Store[T]) Insert(ctx context.Context, id uuid.UUID, value T) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data[id]; ok {
+		return &ConflictError{Field: "id"}
+	}
+	if err := s.checkUnique(id, value); err != nil {
+		return err
+	}
+	if s.maxRecords > 0 && len(s.data) >= s.maxRecords {
+		return &FullError{Max: s.maxRecords}
+	}
+
+	s.data[id] = value
+	if err := s.persist(); err != nil {
+		delete(s.data, id)
+		return err
+	}
+	s.reindex(id, value)
+
+	return nil
+}
+
+func (s *Store[T]) Update(ctx context.Context, id uuid.UUID, value T) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.data[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := s.checkUnique(id, value); err != nil {
+		return err
+	}
+
+	s.data[id] = value
+	if err := s.persist(); err != nil {
+		s.data[id] = previous
+		return err
+	}
+	s.reindex(id, value)
+
+	return nil
+}
+
+// Clear removes every record, restoring them all if the data file can't be written.
+func (s *Store[T]) Clear(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.data
+	s.data = DB[T]{}
+	if err := s.persist(); err != nil {
+		s.data = previous
+		return err
+	}
+	for ix := range s.allIndexes() {
+		ix.reset()
+	}
+
+	return nil
+}
+
+func (s *Store[T]) Delete(ctx context.Context, id uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.data[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	delete(s.data, id)
+	if err := s.persist(); err != nil {
+		s.data[id] = previous
+		return err
+	}
+	s.unindex(id)
+
+	return nil
+}
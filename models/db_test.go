@@ -0,0 +1,119 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFileStoreSurvivesReload(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data.json")
+
+	store, err := NewFileStore[*User](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, gone := uuid.New(), uuid.New()
+	if err := store.Insert(ctx, kept, userWithEmail("jane@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Insert(ctx, gone, userWithEmail("john@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, gone); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewFileStore[*User](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := reloaded.Get(ctx, kept)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *user.Email != "jane@example.com" {
+		t.Errorf("got email %q, want jane@example.com", *user.Email)
+	}
+	if _, err := reloaded.Get(ctx, gone); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v for the deleted user, want ErrNotFound", err)
+	}
+}
+
+func TestFileStoreRefusesCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFileStore[*User](path); err == nil {
+		t.Error("got no error loading a corrupt file")
+	}
+}
+
+func TestListReturnsSortedCopies(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore[*User]()
+	for _, email := range []string{"jane@example.com", "john@example.com", "ada@example.com"} {
+		if err := store.Insert(ctx, uuid.New(), userWithEmail(email)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := store.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.IsSortedFunc(entries, func(a, b Entry[*User]) int { return bytes.Compare(a.ID[:], b.ID[:]) }) {
+		t.Error("got entries out of ID order")
+	}
+
+	*entries[0].Value.Email = "changed@example.com"
+
+	again, err := store.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *again[0].Value.Email == "changed@example.com" {
+		t.Error("mutating a listed record changed the stored one")
+	}
+}
+
+func TestStoreReturnsEarlyWhenCancelled(t *testing.T) {
+	store := NewStore[*User]()
+	id := uuid.New()
+	if err := store.Insert(context.Background(), id, userWithEmail("jane@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, getErr := store.Get(ctx, id)
+	_, getAllErr := store.GetAll(ctx)
+	_, listErr := store.List(ctx)
+	for name, err := range map[string]error{
+		"Get":    getErr,
+		"GetAll": getAllErr,
+		"List":   listErr,
+		"Insert": store.Insert(ctx, uuid.New(), userWithEmail("john@example.com")),
+		"Update": store.Update(ctx, id, userWithEmail("janet@example.com")),
+		"Delete": store.Delete(ctx, id),
+		"Ping":   store.Ping(ctx),
+	} {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: got %v, want context.Canceled", name, err)
+		}
+	}
+
+	user, err := store.Get(context.Background(), id)
+	if err != nil || *user.Email != "jane@example.com" {
+		t.Errorf("got %v, %v, want the untouched user", user, err)
+	}
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var ErrNotIndexed = errors.New("field is not indexed")
+
+// IndexFunc returns the key a record is indexed under, or false to leave it out of the index.
+type IndexFunc[T any] func(value T) (key string, ok bool)
+
+// Finder is implemented by backends that can look records up by an indexed field without
+// scanning them all. FindBy returns an error wrapping ErrNotIndexed for fields without an index.
+type Finder[T any] interface {
+	FindBy(ctx context.Context, field, value string) ([]Entry[T], error)
+}
+
+// index maps keys to the IDs of the records holding them. keys remembers the keys each ID was
+// indexed under, so they can be taken out again without trusting the old value to be unchanged.
+type index[T any] struct {
+	keysOf func(value T) []string
+	ids    map[string]map[uuid.UUID]struct{}
+	keys   map[uuid.UUID][]string
+}
+
+// newIndex indexes the records of data by the keys keysOf gives.
+func newIndex[T any](data DB[T], keysOf func(value T) []string) *index[T] {
+	ix := &index[T]{keysOf: keysOf, ids: map[string]map[uuid.UUID]struct{}{}, keys: map[uuid.UUID][]string{}}
+	for id, value := range data {
+		ix.add(id, value)
+	}
+
+	return ix
+}
+
+func (ix *index[T]) add(id uuid.UUID, value T) {
+	keys := ix.keysOf(value)
+	if len(keys) == 0 {
+		return
+	}
+
+	for _, key := range keys {
+		if ix.ids[key] == nil {
+			ix.ids[key] = map[uuid.UUID]struct{}{}
+		}
+		ix.ids[key][id] = struct{}{}
+	}
+	ix.keys[id] = keys
+}
+
+func (ix *index[T]) remove(id uuid.UUID) {
+	for _, key := range ix.keys[id] {
+		delete(ix.ids[key], id)
+		if len(ix.ids[key]) == 0 {
+			delete(ix.ids, key)
+		}
+	}
+	delete(ix.keys, id)
+}
+
+func (ix *index[T]) reset() {
+	clear(ix.ids)
+	clear(ix.keys)
+}
+
+// AddIndex indexes field by the keys keyOf gives, starting with the records already stored, so
+// FindBy can look them up. Keys are compared ignoring case, like filters.
+func (s *Store[T]) AddIndex(field string, keyOf IndexFunc[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ix := newIndex(s.data, func(value T) []string {
+		key, ok := keyOf(value)
+		if !ok {
+			return nil
+		}
+		return []string{strings.ToLower(key)}
+	})
+
+	if s.indexes == nil {
+		s.indexes = map[string]*index[T]{}
+	}
+	s.indexes[field] = ix
+}
+
+// FindBy returns deep copies of the records whose field is indexed under value, sorted by ID
+// like List.
+func (s *Store[T]) FindBy(ctx context.Context, field, value string) ([]Entry[T], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ix, ok := s.indexes[field]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", field, ErrNotIndexed)
+	}
+
+	ids := ix.ids[strings.ToLower(value)]
+	entries := make([]Entry[T], 0, len(ids))
+	for id := range ids {
+		copied, err := deepCopy(s.data[id])
+		if err != nil {
+			return nil, fmt.Errorf("copying record %s: %w", id, err)
+		}
+		entries = append(entries, Entry[T]{ID: id, Value: copied})
+	}
+
+	slices.SortFunc(entries, func(a, b Entry[T]) int {
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+
+	return entries, nil
+}
+
+// reindex moves id to the keys of value, its new version, in every index. Callers must hold
+// the write lock.
+func (s *Store[T]) reindex(id uuid.UUID, value T) {
+	for ix := range s.allIndexes() {
+		ix.remove(id)
+		ix.add(id, value)
+	}
+}
+
+// unindex drops id, which is no longer stored, from every index. Callers must hold the write lock.
+func (s *Store[T]) unindex(id uuid.UUID) {
+	for ix := range s.allIndexes() {
+		ix.remove(id)
+	}
+}
+
+// allIndexes yields the secondary indexes and, when unique keys are set, their index.
+func (s *Store[T]) allIndexes() iter.Seq[*index[T]] {
+	return func(yield func(*index[T]) bool) {
+		if s.unique != nil && !yield(s.unique) {
+			return
+		}
+		for _, ix := range s.indexes {
+			if !yield(ix) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func emailKey(user *User) (string, bool) {
+	if user.Email == nil {
+		return "", false
+	}
+	return *user.Email, true
+}
+
+func TestFindByFollowsMutations(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore[*User]()
+	store.AddIndex("email", emailKey)
+
+	findIDs := func(value string) []uuid.UUID {
+		t.Helper()
+		entries, err := store.FindBy(ctx, "email", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]uuid.UUID, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.ID
+		}
+		return ids
+	}
+
+	jane, john := uuid.New(), uuid.New()
+	if err := store.Insert(ctx, jane, userWithEmail("jane@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Insert(ctx, john, userWithEmail("john@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if got := findIDs("JANE@example.com"); !slices.Equal(got, []uuid.UUID{jane}) {
+		t.Errorf("after inserts: got %v, want [%s]", got, jane)
+	}
+
+	if err := store.Update(ctx, jane, userWithEmail("janet@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if got := findIDs("jane@example.com"); len(got) != 0 {
+		t.Errorf("after the update: got %v under the old email, want none", got)
+	}
+	if got := findIDs("janet@example.com"); !slices.Equal(got, []uuid.UUID{jane}) {
+		t.Errorf("after the update: got %v under the new email, want [%s]", got, jane)
+	}
+
+	if err := store.Delete(ctx, john); err != nil {
+		t.Fatal(err)
+	}
+	if got := findIDs("john@example.com"); len(got) != 0 {
+		t.Errorf("after the delete: got %v, want none", got)
+	}
+
+	if _, err := store.FindBy(ctx, "last_name", "Doe"); !errors.Is(err, ErrNotIndexed) {
+		t.Errorf("got %v for an unindexed field, want ErrNotIndexed", err)
+	}
+}
+
+func TestAddIndexCoversStoredRecords(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore[*User]()
+	id := uuid.New()
+	if err := store.Insert(ctx, id, userWithEmail("jane@example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	store.AddIndex("email", emailKey)
+
+	entries, err := store.FindBy(ctx, "email", "jane@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ID != id {
+		t.Errorf("got %v, want the user stored before the index", entries)
+	}
+}
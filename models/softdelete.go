@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// SoftDelete is embedded in models whose deletes should only mark the record instead of removing it.
+type SoftDelete struct {
+	DeletedAt *time.Time `json:"deleted_at,omitempty" schema:"readonly"`
+}
+
+func (s *SoftDelete) GetDeletedAt() *time.Time {
+	return s.DeletedAt
+}
+
+func (s *SoftDelete) SetDeletedAt(at *time.Time) {
+	s.DeletedAt = at
+}
@@ -0,0 +1,225 @@
+//go:build cgo
+
+// Package sqlite stores records in SQLite. It needs cgo for the driver, so builds without it
+// leave the package out.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"rocketseat/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+)
+
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Store keeps each record as a JSON document in a table keyed by its UUID.
+// Models with a unique key (see UniqueKey on User) get it in its own UNIQUE column,
+// so duplicates are rejected by SQLite itself and reported as a *models.ConflictError.
+type Store[T any] struct {
+	db    *sql.DB
+	table string
+}
+
+// NewStore opens the database at dsn and creates table if it doesn't exist yet.
+// ":memory:" works too, since the store keeps to a single connection.
+func NewStore[T any](dsn, table string) (*Store[T], error) {
+	if !tableNamePattern.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite database %s: %w", dsn, err)
+	}
+	// every connection to ":memory:" is its own empty database, and sqlite serializes writes anyway
+	db.SetMaxOpenConns(1)
+
+	schema := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id TEXT PRIMARY KEY,
+		unique_key TEXT UNIQUE,
+		data TEXT NOT NULL
+	)`, table)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating table %s: %w", table, err)
+	}
+
+	return &Store[T]{db: db, table: table}, nil
+}
+
+func (s *Store[T]) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store[T]) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *Store[T]) Get(ctx context.Context, id uuid.UUID) (T, error) {
+	var value T
+	var data string
+
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT data FROM %s WHERE id = ?`, s.table), id.String()).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return value, models.ErrNotFound
+	}
+	if err != nil {
+		return value, err
+	}
+
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return value, fmt.Errorf("decoding record %s: %w", id, err)
+	}
+
+	return value, nil
+}
+
+func (s *Store[T]) GetAll(ctx context.Context) (map[uuid.UUID]T, error) {
+	entries, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uuid.UUID]T, len(entries))
+	for _, entry := range entries {
+		result[entry.ID] = entry.Value
+	}
+
+	return result, nil
+}
+
+// List returns every record sorted by ID. Each is decoded fresh from its row, so they're
+// already copies.
+func (s *Store[T]) List(ctx context.Context) ([]models.Entry[T], error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT id, data FROM %s ORDER BY id`, s.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.Entry[T]
+	for rows.Next() {
+		var rawID, data string
+		if err := rows.Scan(&rawID, &data); err != nil {
+			return nil, err
+		}
+
+		id, err := uuid.Parse(rawID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q in table %s: %w", rawID, s.table, err)
+		}
+
+		var value T
+		if err := json.Unmarshal([]byte(data), &value); err != nil {
+			return nil, fmt.Errorf("decoding record %s: %w", id, err)
+		}
+		entries = append(entries, models.Entry[T]{ID: id, Value: value})
+	}
+
+	return entries, rows.Err()
+}
+
+func (s *Store[T]) Insert(ctx context.Context, id uuid.UUID, value T) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		fmt.Sprintf(`INSERT INTO %s (id, unique_key, data) VALUES (?, ?, ?)`, s.table),
+		id.String(), uniqueKeyOf(value), string(data),
+	)
+
+	return mapSQLiteError(err, value)
+}
+
+func (s *Store[T]) Update(ctx context.Context, id uuid.UUID, value T) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		fmt.Sprintf(`UPDATE %s SET unique_key = ?, data = ? WHERE id = ?`, s.table),
+		uniqueKeyOf(value), string(data), id.String(),
+	)
+	if err != nil {
+		return mapSQLiteError(err, value)
+	}
+
+	return requireAffected(result)
+}
+
+func (s *Store[T]) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, s.table), id.String())
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result)
+}
+
+func (s *Store[T]) Clear(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s`, s.table))
+	return err
+}
+
+// uniqueKeyOf returns the value for the unique_key column. Soft-deleted records and models
+// without a key store NULL, which SQLite never counts as a duplicate.
+func uniqueKeyOf(value any) sql.NullString {
+	unique, ok := value.(interface{ UniqueKey() string })
+	if !ok {
+		return sql.NullString{}
+	}
+	if deletable, ok := value.(interface{ GetDeletedAt() *time.Time }); ok && deletable.GetDeletedAt() != nil {
+		return sql.NullString{}
+	}
+
+	key := unique.UniqueKey()
+	return sql.NullString{String: key, Valid: key != ""}
+}
+
+func requireAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+// mapSQLiteError turns constraint violations into a *models.ConflictError naming the field of
+// value that clashed: the id for the primary key, otherwise the model's unique field.
+func mapSQLiteError(err error, value any) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	// a TEXT primary key is enforced by a unique index, so it can fail as either constraint
+	isPrimaryKey := sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey || strings.HasSuffix(sqliteErr.Error(), ".id")
+	switch {
+	case isPrimaryKey:
+		return &models.ConflictError{Field: "id"}
+	case sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique:
+		field := "unique_key"
+		if unique, ok := value.(interface{ UniqueField() string }); ok {
+			field = unique.UniqueField()
+		}
+		return &models.ConflictError{Field: field}
+	}
+
+	return err
+}
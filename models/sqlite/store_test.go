@@ -0,0 +1,86 @@
+//go:build cgo
+
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"rocketseat/models"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func newMemorySQLiteStore(t *testing.T) *Store[*models.User] {
+	t.Helper()
+
+	store, err := NewStore[*models.User](":memory:", "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func userWithEmail(email string) *models.User {
+	return &models.User{Email: &email}
+}
+
+func TestStoreCRUD(t *testing.T) {
+	ctx := context.Background()
+	store := newMemorySQLiteStore(t)
+	id := uuid.New()
+
+	if err := store.Insert(ctx, id, userWithEmail("jane@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Insert(ctx, id, userWithEmail("jane@example.com")); !errors.Is(err, models.ErrConflict) {
+		t.Errorf("got %v inserting the same ID twice, want models.ErrConflict", err)
+	}
+
+	user, err := store.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *user.Email != "jane@example.com" {
+		t.Errorf("got email %q, want jane@example.com", *user.Email)
+	}
+
+	if err := store.Update(ctx, id, userWithEmail("janet@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	all, err := store.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || *all[id].Email != "janet@example.com" {
+		t.Errorf("got %v, want only the updated user", all)
+	}
+
+	if err := store.Delete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, id); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("got %v after delete, want models.ErrNotFound", err)
+	}
+	if err := store.Update(ctx, id, userWithEmail("jane@example.com")); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("got %v updating a deleted user, want models.ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, id); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("got %v deleting twice, want models.ErrNotFound", err)
+	}
+}
+
+func TestStoreRejectsDuplicateEmail(t *testing.T) {
+	ctx := context.Background()
+	store := newMemorySQLiteStore(t)
+
+	if err := store.Insert(ctx, uuid.New(), userWithEmail("jane@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	err := store.Insert(ctx, uuid.New(), userWithEmail("Jane@example.com"))
+	var conflict *models.ConflictError
+	if !errors.As(err, &conflict) || conflict.Field != "email" {
+		t.Errorf("got %v, want a conflict on email", err)
+	}
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound = errors.New("record not found")
+	ErrConflict = errors.New("record conflicts with an existing one")
+	ErrFull     = errors.New("store is full")
+)
+
+// ConflictError is an ErrConflict that names the field the record clashed on.
+type ConflictError struct {
+	Field string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s on %s", ErrConflict, e.Field)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// FullError is an ErrFull that says how many records the store holds at most.
+type FullError struct {
+	Max int
+}
+
+func (e *FullError) Error() string {
+	return fmt.Sprintf("%s, it holds at most %d records", ErrFull, e.Max)
+}
+
+func (e *FullError) Is(target error) bool {
+	return target == ErrFull
+}
+
+// Storage is what the API handlers need from a backend. Get, Update and Delete return
+// ErrNotFound for unknown IDs, and backends that enforce uniqueness return a *ConflictError.
+// Insert checks the ID is free and stores the record in one step, so of concurrent inserts under
+// one ID only the first succeeds and the rest get a *ConflictError on "id".
+// Every method takes the request's context and gives up with its error once it's done.
+type Storage[T any] interface {
+	Get(ctx context.Context, id uuid.UUID) (T, error)
+	GetAll(ctx context.Context) (map[uuid.UUID]T, error)
+	Insert(ctx context.Context, id uuid.UUID, value T) error
+	Update(ctx context.Context, id uuid.UUID, value T) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// Entry pairs a stored value with its ID.
+type Entry[T any] struct {
+	ID    uuid.UUID
+	Value T
+}
+
+// Lister is implemented by backends that can list every record as copies the caller is free
+// to change, sorted by ID so the order is the same from one call to the next.
+type Lister[T any] interface {
+	List(ctx context.Context) ([]Entry[T], error)
+}
+
+// Clearer is implemented by backends that can remove every record in one step.
+type Clearer interface {
+	Clear(ctx context.Context) error
+}
+
+// Capper is implemented by backends that hold at most MaxRecords records, zero meaning no cap.
+type Capper interface {
+	MaxRecords() int
+}
+
+// Expirer is implemented by backends whose records can expire on their own.
+type Expirer interface {
+	Expire(ctx context.Context, id uuid.UUID, ttl time.Duration) error
+}
+
+// Pinger is implemented by backends that can report whether they're reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Timestamps is embedded in models that should carry audit fields.
+// The API sets them, so any values sent by clients are overwritten.
+type Timestamps struct {
+	CreatedAt time.Time `json:"created_at" schema:"readonly"`
+	UpdatedAt time.Time `json:"updated_at" schema:"readonly"`
+}
+
+func (t *Timestamps) GetCreatedAt() time.Time {
+	return t.CreatedAt
+}
+
+func (t *Timestamps) SetCreatedAt(at time.Time) {
+	t.CreatedAt = at
+}
+
+func (t *Timestamps) GetUpdatedAt() time.Time {
+	return t.UpdatedAt
+}
+
+func (t *Timestamps) SetUpdatedAt(at time.Time) {
+	t.UpdatedAt = at
+}
@@ -0,0 +1,210 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TTLStore is an in-memory Store whose records can expire. Expired records are evicted lazily
+// whenever they'd be read, and Sweep removes them all at once for callers that want the memory
+// back sooner. Updates keep a record's expiry; only Expire changes it.
+type TTLStore[T any] struct {
+	*Store[T]
+
+	// mu guards expires and is always taken before the Store's own lock.
+	mu         sync.Mutex
+	expires    map[uuid.UUID]time.Time
+	defaultTTL time.Duration
+}
+
+// NewTTLStore returns an empty store where inserted records expire after defaultTTL. Zero means
+// records only expire when Expire is called for them.
+func NewTTLStore[T any](defaultTTL time.Duration) *TTLStore[T] {
+	return &TTLStore[T]{Store: NewStore[T](), expires: map[uuid.UUID]time.Time{}, defaultTTL: defaultTTL}
+}
+
+// evict removes id if it has expired, reporting whether it did. Callers must hold mu.
+func (s *TTLStore[T]) evict(id uuid.UUID, now time.Time) bool {
+	expiry, ok := s.expires[id]
+	if !ok || now.Before(expiry) {
+		return false
+	}
+
+	delete(s.expires, id)
+	// eviction has to happen even for a cancelled request, and there's no data file to fail
+	_ = s.Store.Delete(context.Background(), id)
+	return true
+}
+
+// evictAll removes every expired record. Callers must hold mu.
+func (s *TTLStore[T]) evictAll() int {
+	now := time.Now()
+	evicted := 0
+	for id := range s.expires {
+		if s.evict(id, now) {
+			evicted++
+		}
+	}
+
+	return evicted
+}
+
+func (s *TTLStore[T]) Get(ctx context.Context, id uuid.UUID) (T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.evict(id, time.Now()) {
+		var zero T
+		return zero, ErrNotFound
+	}
+
+	return s.Store.Get(ctx, id)
+}
+
+func (s *TTLStore[T]) GetAll(ctx context.Context) (map[uuid.UUID]T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evictAll()
+	return s.Store.GetAll(ctx)
+}
+
+func (s *TTLStore[T]) List(ctx context.Context) ([]Entry[T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evictAll()
+	return s.Store.List(ctx)
+}
+
+// FindBy is Store.FindBy leaving out records that have expired.
+func (s *TTLStore[T]) FindBy(ctx context.Context, field, value string) ([]Entry[T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.Store.FindBy(ctx, field, value)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return slices.DeleteFunc(entries, func(entry Entry[T]) bool {
+		return s.evict(entry.ID, now)
+	}), nil
+}
+
+// Insert stores value under id, expiring after the default TTL if there is one. An expired
+// record still holding id doesn't count as a conflict.
+func (s *TTLStore[T]) Insert(ctx context.Context, id uuid.UUID, value T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.evict(id, now)
+	err := s.Store.Insert(ctx, id, value)
+	// expired records still take up room and hold their unique keys until they're evicted
+	if (errors.Is(err, ErrFull) || errors.Is(err, ErrConflict)) && s.evictAll() > 0 {
+		err = s.Store.Insert(ctx, id, value)
+	}
+	if err != nil {
+		return err
+	}
+
+	if s.defaultTTL > 0 {
+		s.expires[id] = now.Add(s.defaultTTL)
+	}
+
+	return nil
+}
+
+func (s *TTLStore[T]) Update(ctx context.Context, id uuid.UUID, value T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.evict(id, time.Now()) {
+		return ErrNotFound
+	}
+
+	err := s.Store.Update(ctx, id, value)
+	if errors.Is(err, ErrConflict) && s.evictAll() > 0 {
+		err = s.Store.Update(ctx, id, value)
+	}
+
+	return err
+}
+
+func (s *TTLStore[T]) Delete(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.evict(id, time.Now()) {
+		return ErrNotFound
+	}
+
+	if err := s.Store.Delete(ctx, id); err != nil {
+		return err
+	}
+	delete(s.expires, id)
+
+	return nil
+}
+
+func (s *TTLStore[T]) Clear(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Store.Clear(ctx); err != nil {
+		return err
+	}
+	clear(s.expires)
+
+	return nil
+}
+
+// Expire makes the record under id expire ttl from now, replacing any expiry it had.
+func (s *TTLStore[T]) Expire(ctx context.Context, id uuid.UUID, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.evict(id, now) {
+		return ErrNotFound
+	}
+	if _, err := s.Store.Get(ctx, id); err != nil {
+		return err
+	}
+
+	s.expires[id] = now.Add(ttl)
+	return nil
+}
+
+// Sweep evicts every expired record, returning how many there were.
+func (s *TTLStore[T]) Sweep(ctx context.Context) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.evictAll()
+}
+
+// RunSweeper calls Sweep every interval until ctx is done. It blocks, so run it in its own goroutine.
+func (s *TTLStore[T]) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if evicted := s.Sweep(ctx); evicted > 0 {
+				slog.DebugContext(ctx, "swept expired records", "count", evicted)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTTLStoreEvictsExpiredRecords(t *testing.T) {
+	ctx := context.Background()
+	store := NewTTLStore[*User](20 * time.Millisecond)
+	short, kept := uuid.New(), uuid.New()
+	if err := store.Insert(ctx, short, userWithEmail("jane@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Insert(ctx, kept, userWithEmail("john@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Expire(ctx, kept, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, short); err != nil {
+		t.Fatalf("got %v before expiry, want the user", err)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	if _, err := store.Get(ctx, short); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v after expiry, want ErrNotFound", err)
+	}
+	if _, err := store.Get(ctx, kept); err != nil {
+		t.Errorf("got %v for the user with a longer TTL, want it kept", err)
+	}
+}
+
+func TestTTLStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	store := NewTTLStore[*User](0)
+	for _, email := range []string{"jane@example.com", "john@example.com"} {
+		id := uuid.New()
+		if err := store.Insert(ctx, id, userWithEmail(email)); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Expire(ctx, id, time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Insert(ctx, uuid.New(), userWithEmail("jim@example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	if n := store.Sweep(ctx); n != 2 {
+		t.Errorf("swept %d records, want 2", n)
+	}
+	if all, _ := store.GetAll(ctx); len(all) != 1 {
+		t.Errorf("got %d records left, want 1", len(all))
+	}
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// UniqueKey is a value that must not repeat in Field across the records a store holds.
+type UniqueKey struct {
+	Field string
+	Key   string
+}
+
+// UniqueKeysFunc returns the keys of value no other record may share. Records that don't take
+// part, like soft-deleted ones, have none.
+type UniqueKeysFunc[T any] func(value T) []UniqueKey
+
+// UniqueEnforcer is implemented by backends that check unique keys in the same step as the
+// write, so two concurrent writes can't both claim a key.
+type UniqueEnforcer[T any] interface {
+	SetUniqueKeys(keysOf UniqueKeysFunc[T])
+}
+
+// SetUniqueKeys makes Insert and Update fail with a *ConflictError naming the field when the
+// record shares a key from keysOf with another one. Nil removes the check.
+func (s *Store[T]) SetUniqueKeys(keysOf UniqueKeysFunc[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.uniqueKeys = keysOf
+	s.unique = nil
+	if keysOf != nil {
+		s.unique = newIndex(s.data, func(value T) []string {
+			keys := keysOf(value)
+			indexed := make([]string, len(keys))
+			for i, key := range keys {
+				indexed[i] = key.indexKey()
+			}
+			return indexed
+		})
+	}
+}
+
+// indexKey is the key k is held under in the unique index, where keys of different fields
+// never collide.
+func (k UniqueKey) indexKey() string {
+	return k.Field + "\x00" + k.Key
+}
+
+// checkUnique returns a *ConflictError when a record other than the one under id shares a
+// unique key with value. Callers must hold the lock.
+func (s *Store[T]) checkUnique(id uuid.UUID, value T) error {
+	if s.uniqueKeys == nil {
+		return nil
+	}
+
+	for _, key := range s.uniqueKeys(value) {
+		for otherID := range s.unique.ids[key.indexKey()] {
+			if otherID != id {
+				return &ConflictError{Field: key.Field}
+			}
+		}
+	}
+
+	return nil
+}
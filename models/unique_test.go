@@ -0,0 +1,77 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func lowerEmail(user *User) []UniqueKey {
+	if user.Email == nil {
+		return nil
+	}
+	return []UniqueKey{{Field: "email", Key: strings.ToLower(*user.Email)}}
+}
+
+func userWithEmail(email string) *User {
+	return &User{Email: &email}
+}
+
+func TestStoreRejectsDuplicateUniqueKeys(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore[*User]()
+	store.SetUniqueKeys(lowerEmail)
+
+	first, second := uuid.New(), uuid.New()
+	if err := store.Insert(ctx, first, userWithEmail("jane@example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := store.Insert(ctx, second, userWithEmail("JANE@example.com"))
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Field != "email" {
+		t.Fatalf("got %v inserting a duplicate, want a conflict on email", err)
+	}
+
+	if err := store.Insert(ctx, second, userWithEmail("john@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Update(ctx, second, userWithEmail("jane@example.com")); !errors.Is(err, ErrConflict) {
+		t.Fatalf("got %v updating to a taken key, want ErrConflict", err)
+	}
+	// a record keeping its own key is no conflict
+	if err := store.Update(ctx, first, userWithEmail("jane@example.com")); err != nil {
+		t.Fatalf("got %v updating a record to its own key", err)
+	}
+}
+
+func TestUniqueKeysFollowStoredRecords(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore[*User]()
+
+	jane := uuid.New()
+	if err := store.Insert(ctx, jane, userWithEmail("jane@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	// records stored before the keys were set hold theirs too
+	store.SetUniqueKeys(lowerEmail)
+	if err := store.Insert(ctx, uuid.New(), userWithEmail("jane@example.com")); !errors.Is(err, ErrConflict) {
+		t.Fatalf("got %v inserting a key stored before SetUniqueKeys, want ErrConflict", err)
+	}
+
+	if err := store.Update(ctx, jane, userWithEmail("janet@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Insert(ctx, uuid.New(), userWithEmail("jane@example.com")); err != nil {
+		t.Fatalf("got %v inserting a key freed by an update", err)
+	}
+	if err := store.Delete(ctx, jane); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Insert(ctx, uuid.New(), userWithEmail("janet@example.com")); err != nil {
+		t.Fatalf("got %v inserting a key freed by a delete", err)
+	}
+}
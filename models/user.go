@@ -1,7 +1,48 @@
 package models
 
+import "strings"
+
 type User struct {
-	FirstName *string `json:"first_name"`
-	LastName  *string `json:"last_name"`
-	Biography *string `json:"biography"`
+	FirstName *string `json:"first_name" validate:"required,max=100"`
+	LastName  *string `json:"last_name" validate:"required,max=100"`
+	Biography *string `json:"biography" validate:"required,max=1000"`
+	Email     *string `json:"email" validate:"required,email" schema:"format=email"`
+	Timestamps
+	SoftDelete
+	Versioning
+}
+
+// Validate checks the rules in the validate tags above.
+func (u *User) Validate() ValidationErrors {
+	return ValidateStruct(u)
+}
+
+// ValidateWith is Validate with maxLengths replacing the max rules of the tags.
+func (u *User) ValidateWith(maxLengths MaxLengths) ValidationErrors {
+	return ValidateStructWith(u, maxLengths)
+}
+
+func (u *User) FilterableFields() []string {
+	return []string{"first_name", "last_name", "email"}
+}
+
+func (u *User) SearchableFields() []string {
+	return []string{"first_name", "last_name", "biography"}
+}
+
+func (u *User) SortableFields() []string {
+	return []string{"first_name", "last_name", "created_at"}
+}
+
+func (u *User) UniqueField() string {
+	return "email"
+}
+
+// UniqueKey is the lowercased email, so addresses differing only in case count as duplicates.
+func (u *User) UniqueKey() string {
+	if u.Email == nil {
+		return ""
+	}
+
+	return strings.ToLower(*u.Email)
 }
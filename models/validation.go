@@ -0,0 +1,152 @@
+package models
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	// Limit is the bound a min or max rule was broken against.
+	Limit int `json:"limit,omitempty"`
+}
+
+// ValidationErrors collects every problem found with a model so clients can fix them in one go.
+type ValidationErrors []FieldError
+
+func (v *ValidationErrors) Add(field, message string) {
+	*v = append(*v, FieldError{Field: field, Message: message})
+}
+
+func (v *ValidationErrors) addLimit(field, message string, limit int) {
+	*v = append(*v, FieldError{Field: field, Message: message, Limit: limit})
+}
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, 0, len(v))
+	for _, fieldErr := range v {
+		messages = append(messages, fieldErr.Field+": "+fieldErr.Message)
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// MaxLengths sets the most characters a string field may hold, by JSON name, replacing any
+// max=N rule its tag has.
+type MaxLengths map[string]int
+
+// ValidateStruct checks v's fields against their validate tags, a comma-separated list of:
+//
+//	required  the field must be set; for pointers that means non-nil, otherwise non-zero
+//	min=N     strings must have at least N characters
+//	max=N     strings must have at most N characters
+//	email     strings must be a bare address like name@example.com
+//
+// Lengths are counted in runes, not bytes. Fields are reported by their JSON names. Embedded
+// structs are flattened like encoding/json does, and nested structs are checked too, reported
+// as "parent.child".
+func ValidateStruct(v any) ValidationErrors {
+	return ValidateStructWith(v, nil)
+}
+
+// ValidateStructWith is ValidateStruct with maxLengths taking precedence over the max rules in
+// the tags.
+func ValidateStructWith(v any, maxLengths MaxLengths) ValidationErrors {
+	var errs ValidationErrors
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return errs
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return errs
+	}
+
+	validateFields(rv, "", maxLengths, &errs)
+	return errs
+}
+
+func validateFields(rv reflect.Value, prefix string, maxLengths MaxLengths, errs *ValidationErrors) {
+	t := rv.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		value := rv.Field(i)
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct && field.Type != timeType {
+			validateFields(value, prefix, maxLengths, errs)
+			continue
+		}
+
+		name := tag
+		if name == "" {
+			name = field.Name
+		}
+		name = prefix + name
+
+		validateField(value, name, field.Tag.Get("validate"), maxLengths, errs)
+	}
+}
+
+func validateField(value reflect.Value, name, tag string, maxLengths MaxLengths, errs *ValidationErrors) {
+	set := !value.IsZero()
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	rules := strings.Split(tag, ",")
+	if n, ok := maxLengths[name]; ok {
+		rules = slices.DeleteFunc(rules, func(rule string) bool {
+			return strings.HasPrefix(strings.TrimSpace(rule), "max=")
+		})
+		rules = append(rules, "max="+strconv.Itoa(n))
+	}
+
+	for _, rule := range rules {
+		key, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if key == "required" && !set {
+			errs.Add(name, "required")
+			return
+		}
+
+		if value.Kind() != reflect.String || !set {
+			continue
+		}
+		text := value.String()
+
+		switch key {
+		case "min":
+			if n, err := strconv.Atoi(arg); err == nil && utf8.RuneCountInString(text) < n {
+				errs.addLimit(name, fmt.Sprintf("must be at least %d characters", n), n)
+			}
+		case "max":
+			if n, err := strconv.Atoi(arg); err == nil && utf8.RuneCountInString(text) > n {
+				errs.addLimit(name, fmt.Sprintf("must be at most %d characters", n), n)
+			}
+		case "email":
+			// ParseAddress also accepts display-name forms like "Jane <jane@example.com>", so require a bare address
+			if addr, err := mail.ParseAddress(text); err != nil || addr.Address != text {
+				errs.Add(name, "must be a valid address like name@example.com")
+			}
+		}
+	}
+
+	if value.Kind() == reflect.Struct && value.Type() != timeType {
+		validateFields(value, name+".", maxLengths, errs)
+	}
+}
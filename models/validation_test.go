@@ -0,0 +1,57 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+type address struct {
+	City string `json:"city" validate:"required,max=5"`
+}
+
+type profile struct {
+	Name    *string `json:"name" validate:"required,min=2,max=4"`
+	Nick    string  `json:"nick" validate:"max=3"`
+	Address address `json:"address"`
+	Timestamps
+}
+
+func fieldsOf(errs ValidationErrors) []string {
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	return fields
+}
+
+func TestValidateStructTags(t *testing.T) {
+	name := func(s string) *string { return &s }
+
+	tests := []struct {
+		name  string
+		value profile
+		want  []string
+	}{
+		{"valid", profile{Name: name("Jane"), Nick: "JD", Address: address{City: "Rio"}}, nil},
+		{"missing required", profile{Address: address{City: "Rio"}}, []string{"name"}},
+		{"too short", profile{Name: name("J"), Address: address{City: "Rio"}}, []string{"name"}},
+		{"too long", profile{Name: name("Janet"), Nick: "Jay-D", Address: address{City: "Rio"}}, []string{"name", "nick"}},
+		{"nested", profile{Name: name("Jane"), Address: address{City: "Lisbon"}}, []string{"address.city"}},
+		{"nested missing", profile{Name: name("Jane")}, []string{"address.city"}},
+	}
+	for _, tt := range tests {
+		if got := fieldsOf(ValidateStruct(&tt.value)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got errors on %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMaxLengthsReplaceTags(t *testing.T) {
+	name := "Jane"
+	value := profile{Name: &name, Address: address{City: "Rio"}}
+
+	errs := ValidateStructWith(&value, MaxLengths{"name": 3})
+	if len(errs) != 1 || errs[0].Field != "name" || errs[0].Limit != 3 {
+		t.Errorf("got %+v, want name over its limit of 3", errs)
+	}
+}
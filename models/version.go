@@ -0,0 +1,15 @@
+package models
+
+// Versioning is embedded in models that use optimistic concurrency control. Version starts at 1
+// and goes up by one on every change, so a write based on an older version can be refused.
+type Versioning struct {
+	Version int `json:"version"`
+}
+
+func (v *Versioning) GetVersion() int {
+	return v.Version
+}
+
+func (v *Versioning) SetVersion(version int) {
+	v.Version = version
+}
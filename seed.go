@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"rocketseat/api"
+	"rocketseat/models"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// seedColumns maps the CSV header names seedUsers understands to the field each fills in.
+var seedColumns = map[string]func(*models.User, string){
+	"first_name": func(u *models.User, v string) { u.FirstName = &v },
+	"last_name":  func(u *models.User, v string) { u.LastName = &v },
+	"biography":  func(u *models.User, v string) { u.Biography = &v },
+	"bio":        func(u *models.User, v string) { u.Biography = &v },
+	"email":      func(u *models.User, v string) { u.Email = &v },
+}
+
+// seedUsers loads the users in the CSV file at path into db, with the IDs, length limits and
+// unique fields the API uses under cfg. The first row names the columns. Every row is checked
+// before anything is inserted, so a bad file leaves the store untouched and the error lists
+// each bad row by its line number. An insert failing part way takes back the ones before it.
+func seedUsers(ctx context.Context, db models.Storage[*models.User], path string, cfg api.Config) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("opening seed file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("reading seed file header: %w", err)
+	}
+
+	setters := make([]func(*models.User, string), len(header))
+	for i, name := range header {
+		setter, ok := seedColumns[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return 0, fmt.Errorf("seed file has unknown column %q", name)
+		}
+		setters[i] = setter
+	}
+
+	existing, err := db.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	keysOf := api.UniqueKeys(cfg)
+	taken := map[models.UniqueKey]bool{}
+	for _, user := range existing {
+		for _, key := range keysOf(user) {
+			taken[key] = true
+		}
+	}
+
+	var users []*models.User
+	var problems []string
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		user := &models.User{}
+		for i, value := range record {
+			setters[i](user, value)
+		}
+
+		if errs := user.ValidateWith(cfg.MaxLengths); len(errs) > 0 {
+			problems = append(problems, fmt.Sprintf("line %d: %s", line, errs.Error()))
+			continue
+		}
+		keys := keysOf(user)
+		if i := slices.IndexFunc(keys, func(key models.UniqueKey) bool { return taken[key] }); i >= 0 {
+			problems = append(problems, fmt.Sprintf("line %d: %s: already exists", line, keys[i].Field))
+			continue
+		}
+		for _, key := range keys {
+			taken[key] = true
+		}
+
+		users = append(users, user)
+	}
+	if len(problems) > 0 {
+		return 0, fmt.Errorf("seed file %s has invalid rows:\n%s", path, strings.Join(problems, "\n"))
+	}
+
+	var ids api.IDGenerator = api.UUIDv4{}
+	if cfg.IDGenerator != nil {
+		ids = cfg.IDGenerator
+	}
+
+	now := time.Now().UTC()
+	inserted := make([]uuid.UUID, 0, len(users))
+	for _, user := range users {
+		user.SetVersion(1)
+		user.SetCreatedAt(now)
+		user.SetUpdatedAt(now)
+
+		id := ids.New()
+		if err := db.Insert(ctx, id, user); err != nil {
+			err = fmt.Errorf("inserting seeded user: %w", err)
+			for _, id := range inserted {
+				if deleteErr := db.Delete(ctx, id); deleteErr != nil {
+					err = errors.Join(err, fmt.Errorf("removing seeded user %s: %w", id, deleteErr))
+				}
+			}
+			return 0, err
+		}
+		inserted = append(inserted, id)
+	}
+
+	return len(users), nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"rocketseat/api"
+	"rocketseat/models"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func writeSeedFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "users.csv")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSeedUsersAreQueryable(t *testing.T) {
+	path := writeSeedFile(t, "first_name,last_name,bio,email\n"+
+		"Jane,Doe,Writes things,jane@example.com\n"+
+		"John,Roe,Reads things,john@example.com\n")
+	db := models.NewStore[*models.User]()
+
+	count, err := seedUsers(context.Background(), db, path, api.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("got count %d, want 2", count)
+	}
+
+	rec := httptest.NewRecorder()
+	api.NewHandler(db, api.DefaultConfig()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users?first_name=jane", nil))
+	var list struct {
+		Data []models.User `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Data) != 1 || *list.Data[0].Biography != "Writes things" {
+		t.Errorf("got %+v, want Jane with her bio", list.Data)
+	}
+}
+
+func TestSeedRejectsBadRowsByLine(t *testing.T) {
+	path := writeSeedFile(t, "first_name,last_name,bio,email\n"+
+		"Jane,Doe,Writes things,jane@example.com\n"+
+		"John,Roe,Reads things,not-an-email\n")
+	db := models.NewStore[*models.User]()
+
+	_, err := seedUsers(context.Background(), db, path, api.DefaultConfig())
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Fatalf("got error %v, want one naming line 3", err)
+	}
+	if all, _ := db.GetAll(context.Background()); len(all) != 0 {
+		t.Errorf("got %d users stored, want none", len(all))
+	}
+}
+
+func TestSeedUsesConfiguredIDsAndUniqueFields(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.IDGenerator = api.UUIDv7{}
+	cfg.UniqueFields = []string{"last_name"}
+
+	db := models.NewStore[*models.User]()
+	path := writeSeedFile(t, "first_name,last_name,bio,email\nJane,Doe,Writes things,jane@example.com\n")
+	if _, err := seedUsers(context.Background(), db, path, cfg); err != nil {
+		t.Fatal(err)
+	}
+	all, _ := db.GetAll(context.Background())
+	for id := range all {
+		if id.Version() != 7 {
+			t.Errorf("got a version %d ID, want 7", id.Version())
+		}
+	}
+
+	// differs only in case, which the API counts as the same value
+	path = writeSeedFile(t, "first_name,last_name,bio,email\nJohn,DOE,Reads things,john@example.com\n")
+	_, err := seedUsers(context.Background(), db, path, cfg)
+	if err == nil || !strings.Contains(err.Error(), "last_name: already exists") {
+		t.Fatalf("got error %v, want last_name to already exist", err)
+	}
+
+	cfg.UniqueCaseSensitive = true
+	if _, err := seedUsers(context.Background(), db, path, cfg); err != nil {
+		t.Errorf("got error %v with case-sensitive unique fields, want none", err)
+	}
+}
+
+// failingStore fails every insert after the first few.
+type failingStore struct {
+	*models.Store[*models.User]
+	inserts int
+}
+
+func (s *failingStore) Insert(ctx context.Context, id uuid.UUID, user *models.User) error {
+	if s.inserts == 0 {
+		return errors.New("disk full")
+	}
+	s.inserts--
+	return s.Store.Insert(ctx, id, user)
+}
+
+func TestSeedTakesBackInsertsWhenOneFails(t *testing.T) {
+	path := writeSeedFile(t, "first_name,last_name,bio,email\n"+
+		"Jane,Doe,Writes things,jane@example.com\n"+
+		"John,Roe,Reads things,john@example.com\n"+
+		"Jim,Poe,Writes poems,jim@example.com\n")
+	db := &failingStore{Store: models.NewStore[*models.User](), inserts: 2}
+
+	if _, err := seedUsers(context.Background(), db, path, api.DefaultConfig()); err == nil {
+		t.Fatal("got no error, want the failed insert's")
+	}
+	if all, _ := db.GetAll(context.Background()); len(all) != 0 {
+		t.Errorf("got %d users stored, want none", len(all))
+	}
+}
@@ -0,0 +1,18 @@
+//go:build cgo
+
+package main
+
+import (
+	"rocketseat/models"
+	"rocketseat/models/sqlite"
+)
+
+// newSQLiteStorage keeps users in the users table of the SQLite database at dsn.
+func newSQLiteStorage(dsn string) (models.Storage[*models.User], error) {
+	store, err := sqlite.NewStore[*models.User](dsn, "users")
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
@@ -0,0 +1,13 @@
+//go:build !cgo
+
+package main
+
+import (
+	"errors"
+	"rocketseat/models"
+)
+
+// newSQLiteStorage fails, since the SQLite driver needs cgo and this build has none.
+func newSQLiteStorage(string) (models.Storage[*models.User], error) {
+	return nil, errors.New("the sqlite backend needs a build with cgo enabled")
+}